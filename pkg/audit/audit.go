@@ -0,0 +1,216 @@
+// Package audit keeps a durable record of the decisions and actions of node refiner, written
+// as JSON lines to a file, a ConfigMap ring buffer or stdout
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Kinds of records
+const (
+	// KindDecision is a decision of the drainer whether to scale the cluster down
+	KindDecision = "decision"
+	// KindScaleDown is a scale down: the cordon and the drain of a node
+	KindScaleDown = "scaleDown"
+	// KindUncordon is a node uncordoned for pods that fit nowhere else
+	KindUncordon = "uncordon"
+	// KindAdmin is a request to the admin API
+	KindAdmin = "admin"
+)
+
+// DefaultQueueSize is the number of records waiting to be written, records are dropped beyond it
+const DefaultQueueSize = 100
+
+// Results of the records
+const (
+	ResultAllowed   = "allowed"
+	ResultRefused   = "refused"
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+)
+
+// Pod is a pod evicted by a scale down
+type Pod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Owner is the kind and name of the controller of the pod, like ReplicaSet/web-5d4f8
+	Owner string `json:"owner,omitempty"`
+}
+
+// Record is a decision or an action of node refiner
+type Record struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	Node string    `json:"node,omitempty"`
+	// Reason of a decision, or the request of an admin
+	Reason string `json:"reason,omitempty"`
+	// SettingsHash identifies the drainer settings in effect
+	SettingsHash string `json:"settingsHash,omitempty"`
+	// User who sent an admin request
+	User            string        `json:"user,omitempty"`
+	Pods            []Pod         `json:"pods,omitempty"`
+	Result          string        `json:"result"`
+	Duration        time.Duration `json:"-"`
+	DurationSeconds float64       `json:"durationSeconds,omitempty"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// Auditor is told about the decisions and actions
+type Auditor interface {
+	Audit(record Record)
+}
+
+// Sink stores the records
+type Sink interface {
+	Write(record Record) error
+}
+
+// Options selects the sinks of the audit log, it is disabled when none is set
+type Options struct {
+	// File the records are appended to
+	File string
+	// ConfigMap, as namespace/name, keeping the latest ConfigMapSize records
+	ConfigMap     string
+	ConfigMapSize int
+	// Stdout writes the records to the standard output
+	Stdout bool
+}
+
+// Enabled reports whether a sink is set
+func (o Options) Enabled() bool {
+	return o.File != "" || o.ConfigMap != "" || o.Stdout
+}
+
+// Logger writes every record to all its sinks. The records are queued and written once it runs,
+// so that slow sinks, like the ConfigMap, don't hold up the calculation loop or the drains
+type Logger struct {
+	sinks []Sink
+	queue chan Record
+}
+
+// NewLogger returns a logger writing to the sinks
+func NewLogger(sinks ...Sink) *Logger {
+	return &Logger{sinks: sinks, queue: make(chan Record, DefaultQueueSize)}
+}
+
+// New returns a logger writing to the sinks of the options, nil if none is set. The client is
+// only used by the ConfigMap sink
+func New(opts Options, client kubernetes.Interface) (*Logger, error) {
+	var sinks []Sink
+	if opts.File != "" {
+		sink, err := NewFileSink(opts.File)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if opts.ConfigMap != "" {
+		sink, err := NewConfigMapSink(client, opts.ConfigMap, opts.ConfigMapSize)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if opts.Stdout {
+		sinks = append(sinks, NewWriterSink(os.Stdout))
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return NewLogger(sinks...), nil
+}
+
+// Audit queues the record, it is dropped when the queue is full
+func (l *Logger) Audit(record Record) {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	record.DurationSeconds = record.Duration.Seconds()
+
+	select {
+	case l.queue <- record:
+	default:
+		zap.S().Warnw("Audit queue is full, dropping the record", "kind", record.Kind, "node", record.Node)
+	}
+}
+
+// Run writes the queued records to every sink until stopCh is closed, then the records left
+// before closing the sinks. Failures are logged
+func (l *Logger) Run(stopCh <-chan struct{}) {
+	for {
+		select {
+		case record := <-l.queue:
+			l.write(record)
+		case <-stopCh:
+			for {
+				select {
+				case record := <-l.queue:
+					l.write(record)
+				default:
+					l.close()
+					return
+				}
+			}
+		}
+	}
+}
+
+// close closes the sinks holding resources, like the audit file
+func (l *Logger) close() {
+	for _, sink := range l.sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				zap.S().Warnw("Unable to close the audit sink", "error", err)
+			}
+		}
+	}
+}
+
+func (l *Logger) write(record Record) {
+	for _, sink := range l.sinks {
+		if err := sink.Write(record); err != nil {
+			zap.S().Warnw("Unable to write the audit record", "kind", record.Kind, "node", record.Node, "error", err)
+		}
+	}
+}
+
+// ReadRecords reads JSON lines records
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, errors.Wrapf(err, "invalid audit record on line %d", line)
+		}
+		record.Duration = time.Duration(record.DurationSeconds * float64(time.Second))
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// Filter selects records, the zero value selects all of them
+type Filter struct {
+	Node  string
+	Kind  string
+	Since time.Time
+}
+
+// Match reports whether the record is selected
+func (f Filter) Match(record Record) bool {
+	return (f.Node == "" || record.Node == f.Node) &&
+		(f.Kind == "" || record.Kind == f.Kind) &&
+		!record.Time.Before(f.Since)
+}
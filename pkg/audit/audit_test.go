@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testRecords() []Record {
+	start := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []Record{
+		{Time: start, Kind: KindDecision, Node: "a", Reason: "all conditions passed", SettingsHash: "0123456789ab", Result: ResultAllowed},
+		{Time: start.Add(time.Minute), Kind: KindScaleDown, Node: "a", Result: ResultSucceeded, Duration: 90 * time.Second,
+			Pods: []Pod{{Namespace: "default", Name: "web-1", Owner: "ReplicaSet/web"}}},
+		{Time: start.Add(2 * time.Minute), Kind: KindAdmin, Reason: "pause", User: "admin", Result: ResultSucceeded},
+	}
+}
+
+// TestFileSink tests that the records appended to a file are read back and filtered
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for i := 0; i < 2; i++ {
+		// The records of a restarted controller are appended
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger := NewLogger(sink)
+		for _, record := range testRecords() {
+			logger.Audit(record)
+		}
+		// A stopped logger writes the records left and closes the file
+		stopCh := make(chan struct{})
+		close(stopCh)
+		logger.Run(stopCh)
+		if err := sink.Close(); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("Expected the file to be closed, got %v", err)
+		}
+	}
+
+	records, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 6 {
+		t.Fatalf("Expected 6 records, got %d", len(records))
+	}
+	scaleDown := records[1]
+	if scaleDown.Duration != 90*time.Second || len(scaleDown.Pods) != 1 || scaleDown.Pods[0].Owner != "ReplicaSet/web" {
+		t.Errorf("Unexpected scale down record %+v", scaleDown)
+	}
+
+	matched := 0
+	filter := Filter{Node: "a", Kind: KindScaleDown, Since: testRecords()[1].Time}
+	for _, record := range records {
+		if filter.Match(record) {
+			matched++
+		}
+	}
+	if matched != 2 {
+		t.Errorf("Expected the 2 scale downs to match, got %d", matched)
+	}
+}
+
+// TestConfigMapSink tests that the ConfigMap is created on the first write and keeps the latest records
+func TestConfigMapSink(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	sink, err := NewConfigMapSink(client, "kube-system/node-refiner-audit", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := sink.Write(Record{Kind: KindDecision, Reason: fmt.Sprint(i), Result: ResultRefused}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := ReadConfigMap(context.TODO(), client, "kube-system/node-refiner-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0].Reason != "2" || records[2].Reason != "4" {
+		t.Errorf("Expected the 3 latest records, got %+v", records)
+	}
+
+	for _, ref := range []string{"node-refiner-audit", "/name", "a/b/c"} {
+		if _, err := NewConfigMapSink(client, ref, 0); err == nil {
+			t.Errorf("Expected %q to be refused", ref)
+		}
+	}
+}
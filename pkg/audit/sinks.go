@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Defaults of the ConfigMap sink
+const (
+	DefaultConfigMapSize = 1000
+	// ConfigMapKey is the key of the ConfigMap holding the records as JSON lines
+	ConfigMapKey = "records.jsonl"
+	// maxConfigMapBytes keeps the ConfigMap below the 1MiB limit of the API server
+	maxConfigMapBytes = 900 * 1024
+)
+
+// WriterSink writes the records as JSON lines
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a sink writing to w, like os.Stdout
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write implements Sink
+func (s *WriterSink) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// FileSink appends the records as JSON lines to a file
+type FileSink struct {
+	*WriterSink
+	file *os.File
+}
+
+// NewFileSink opens the file to append the records to, creating it if needed
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open the audit file")
+	}
+	return &FileSink{WriterSink: NewWriterSink(file), file: file}, nil
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// ReadFile reads the records of an audit file
+func ReadFile(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open the audit file")
+	}
+	defer file.Close()
+	records, err := ReadRecords(file)
+	return records, errors.Wrapf(err, "cannot read the audit file %s", path)
+}
+
+// ConfigMapSink keeps the latest records in a ConfigMap, a ring buffer surviving restarts
+type ConfigMapSink struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	size      int
+}
+
+// NewConfigMapSink returns a sink keeping the size latest records in the ConfigMap given as
+// namespace/name, DefaultConfigMapSize when size isn't positive. The ConfigMap is created on the first write
+func NewConfigMapSink(client kubernetes.Interface, ref string, size int) (*ConfigMapSink, error) {
+	namespace, name, err := ParseConfigMapRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		size = DefaultConfigMapSize
+	}
+	return &ConfigMapSink{client: client, namespace: namespace, name: name, size: size}, nil
+}
+
+// ParseConfigMapRef splits namespace/name
+func ParseConfigMapRef(ref string) (string, string, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.Errorf("invalid ConfigMap %q, expected namespace/name", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Write implements Sink
+func (s *ConfigMapSink) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: s.name},
+				Data:       map[string]string{ConfigMapKey: string(line) + "\n"},
+			}
+			_, err = s.client.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Turned into a conflict so that the write is retried on the existing ConfigMap
+				return apierrors.NewConflict(corev1.Resource("configmaps"), s.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[ConfigMapKey] = s.append(cm.Data[ConfigMapKey], line)
+		_, err = s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// append adds the line to the records, dropping the oldest beyond the size of the ring buffer
+func (s *ConfigMapSink) append(records string, line []byte) string {
+	lines := strings.Split(strings.TrimSuffix(records, "\n"), "\n")
+	if lines[0] == "" {
+		lines = lines[:0]
+	}
+	lines = append(lines, string(line))
+	total := 0
+	for _, l := range lines {
+		total += len(l) + 1
+	}
+	for len(lines) > 1 && (len(lines) > s.size || total > maxConfigMapBytes) {
+		total -= len(lines[0]) + 1
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ReadConfigMap reads the records kept in the ConfigMap given as namespace/name
+func ReadConfigMap(ctx context.Context, client kubernetes.Interface, ref string) ([]Record, error) {
+	namespace, name, err := ParseConfigMapRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get the audit ConfigMap")
+	}
+	records, err := ReadRecords(bytes.NewBufferString(cm.Data[ConfigMapKey]))
+	return records, errors.Wrapf(err, "cannot read the audit ConfigMap %s", ref)
+}
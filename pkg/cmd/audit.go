@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/audit"
+	"github.com/SAP/node-refiner/pkg/common"
+	"github.com/SAP/node-refiner/pkg/types"
+	"github.com/pkg/errors"
+)
+
+// auditFlags are the flags of the audit command
+type auditFlags struct {
+	clusterFlags
+	file      string
+	configMap string
+	filter    audit.Filter
+	since     time.Duration
+	limit     int
+	output    types.OutputFormat
+}
+
+func auditCommand(args []string) error {
+	var af auditFlags
+
+	fs := newFlagSet("audit")
+	af.bind(fs, "error")
+	fs.StringVar(&af.file, "file", envString("", "NODE_REFINER_AUDIT_FILE"), "Audit file to read ($NODE_REFINER_AUDIT_FILE)")
+	fs.StringVar(&af.configMap, "config-map", envString("", "NODE_REFINER_AUDIT_CONFIG_MAP"),
+		"Audit ConfigMap to read, as namespace/name ($NODE_REFINER_AUDIT_CONFIG_MAP)")
+	fs.StringVar(&af.filter.Node, "node", "", "Only print the records of the node")
+	fs.StringVar(&af.filter.Kind, "kind", "", fmt.Sprintf("Only print the records of a kind: %s, %s, %s or %s",
+		audit.KindDecision, audit.KindScaleDown, audit.KindUncordon, audit.KindAdmin))
+	fs.DurationVar(&af.since, "since", 0, "Only print the records of the last duration, like 24h")
+	fs.IntVar(&af.limit, "limit", 0, "Only print the latest records, all when 0")
+	af.output = types.OutputTable
+	fs.Var(&af.output, "output", fmt.Sprintf("Output format, one of %v", types.OutputFormats))
+	fs.Var(&af.output, "o", "Shorthand for --output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.Errorf("unexpected arguments %v", fs.Args())
+	}
+	if (af.file == "") == (af.configMap == "") {
+		return errors.New("exactly one of --file and --config-map is required")
+	}
+
+	flush, err := setupLogger(af.logLevel, af.logFormat)
+	if err != nil {
+		return err
+	}
+	defer flush()
+
+	var records []audit.Record
+	if af.file != "" {
+		records, err = audit.ReadFile(af.file)
+	} else {
+		client, clientErr := common.GetClientFor(af.kubeconfig, af.context)
+		if clientErr != nil {
+			return clientErr
+		}
+		records, err = audit.ReadConfigMap(context.Background(), client, af.configMap)
+	}
+	if err != nil {
+		return err
+	}
+
+	if af.since > 0 {
+		af.filter.Since = time.Now().Add(-af.since)
+	}
+	selected := make([]audit.Record, 0, len(records))
+	for _, record := range records {
+		if af.filter.Match(record) {
+			selected = append(selected, record)
+		}
+	}
+	if af.limit > 0 && len(selected) > af.limit {
+		selected = selected[len(selected)-af.limit:]
+	}
+	return writeAudit(os.Stdout, af.output, selected)
+}
+
+// writeAudit prints the records oldest first
+func writeAudit(w io.Writer, format types.OutputFormat, records []audit.Record) error {
+	if format.IsStructured() {
+		return types.WriteStructured(w, format, records)
+	}
+
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		pods := make([]string, 0, len(r.Pods))
+		for _, p := range r.Pods {
+			pods = append(pods, p.Namespace+"/"+p.Name)
+		}
+		duration := ""
+		if r.Duration > 0 {
+			duration = r.Duration.Round(time.Second).String()
+		}
+		rows = append(rows, []string{
+			r.Time.Format(time.RFC3339),
+			r.Kind,
+			r.Node,
+			r.Result,
+			r.Reason,
+			strings.Join(pods, " "),
+			duration,
+			r.SettingsHash,
+			r.User,
+			r.Error,
+		})
+	}
+	return types.WriteRows(w, format, "Audit Log",
+		[]string{"Time", "Kind", "Node", "Result", "Reason", "Pods Evicted", "Duration", "Settings", "User", "Error"}, rows)
+}
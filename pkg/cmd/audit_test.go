@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/audit"
+)
+
+// writeAuditFile appends records to an audit file like the controller does and returns its path
+func writeAuditFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := audit.NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := audit.NewLogger(sink)
+	now := time.Now().UTC()
+	for _, record := range []audit.Record{
+		{Time: now.Add(-48 * time.Hour), Kind: audit.KindDecision, Node: "node-a", Result: audit.ResultAllowed},
+		{Time: now.Add(-2 * time.Hour), Kind: audit.KindScaleDown, Node: "node-a", Result: audit.ResultSucceeded,
+			Pods: []audit.Pod{{Namespace: "default", Name: "web-1"}}},
+		{Time: now.Add(-time.Hour), Kind: audit.KindAdmin, Reason: "pause", User: "admin", Result: audit.ResultSucceeded},
+	} {
+		logger.Audit(record)
+	}
+	stopCh := make(chan struct{})
+	close(stopCh)
+	// The stopped logger closes the file once the records are written
+	logger.Run(stopCh)
+	return path
+}
+
+// TestAuditCommand tests that the records of an audit file are filtered and printed
+func TestAuditCommand(t *testing.T) {
+	path := writeAuditFile(t)
+
+	tests := []struct {
+		name  string
+		args  []string
+		kinds []string
+	}{
+		{name: "all", kinds: []string{audit.KindDecision, audit.KindScaleDown, audit.KindAdmin}},
+		{name: "node", args: []string{"--node", "node-a"}, kinds: []string{audit.KindDecision, audit.KindScaleDown}},
+		{name: "kind", args: []string{"--kind", audit.KindAdmin}, kinds: []string{audit.KindAdmin}},
+		{name: "since", args: []string{"--since", "24h"}, kinds: []string{audit.KindScaleDown, audit.KindAdmin}},
+		{name: "limit", args: []string{"--limit", "1"}, kinds: []string{audit.KindAdmin}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := captureStdout(t, func() error { return auditCommand(append([]string{"--file", path, "-o", "json"}, tt.args...)) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			var records []audit.Record
+			if err := json.Unmarshal([]byte(out), &records); err != nil {
+				t.Fatalf("Invalid records %q: %v", out, err)
+			}
+			kinds := make([]string, 0, len(records))
+			for _, record := range records {
+				kinds = append(kinds, record.Kind)
+			}
+			if strings.Join(kinds, ",") != strings.Join(tt.kinds, ",") {
+				t.Errorf("Expected %v, got %v", tt.kinds, kinds)
+			}
+		})
+	}
+
+	out, err := captureStdout(t, func() error { return auditCommand([]string{"--file", path, "--kind", audit.KindScaleDown}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "default/web-1") {
+		t.Errorf("Expected the evicted pod in the table:\n%s", out)
+	}
+
+	setenv(t, "NODE_REFINER_AUDIT_FILE", "")
+	setenv(t, "NODE_REFINER_AUDIT_CONFIG_MAP", "")
+	if err := auditCommand(nil); err == nil || !strings.Contains(err.Error(), "exactly one of") {
+		t.Errorf("Expected an error without a source, got %v", err)
+	}
+}
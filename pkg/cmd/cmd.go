@@ -0,0 +1,98 @@
+// Package cmd implements the command line interface of node refiner
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// command is a subcommand of the node refiner binary
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{name: "run", description: "Run the node refiner controller (default)", run: runCommand},
+	{name: "report", description: "Print a one-shot analysis of the cluster and the drainer decision", run: reportCommand},
+	{name: "audit", description: "Print the recorded decisions and actions of the controller", run: auditCommand},
+	{name: "simulate", description: "Replay recorded cluster snapshots to see which drains a set of settings would cause", run: simulateCommand},
+	{name: "version", description: "Print the version information", run: versionCommand},
+}
+
+// Execute parses the arguments (without the program name), runs the requested
+// subcommand and returns the exit code of the process
+func Execute(args []string) int {
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage(os.Stdout)
+		return 0
+	}
+
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(args); err != nil {
+				if err == flag.ErrHelp {
+					return 0
+				}
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+	usage(os.Stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: node-refiner <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintf(w, "\nUse \"node-refiner <command> -h\" for more information about a command.\n")
+}
+
+// newFlagSet creates the flag set of a subcommand
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("node-refiner "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// envString returns the value of the first set environment variable, or the default value
+func envString(def string, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := os.LookupEnv(key); ok && value != "" {
+			return value
+		}
+	}
+	return def
+}
+
+// envDuration returns the parsed value of an environment variable, or the default value when
+// it is not set. Like an invalid flag, an invalid value is an error
+func envDuration(def time.Duration, key string) (time.Duration, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid value %q for $%s", value, key)
+	}
+	return d, nil
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// setenv sets an environment variable for the duration of the test, or unsets it when value is empty
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// captureStdout returns what run printed to the standard output
+func captureStdout(t *testing.T, run func() error) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&out, r)
+		close(done)
+	}()
+	err = run()
+	w.Close()
+	<-done
+	r.Close()
+	return out.String(), err
+}
+
+// writeFile writes the content to a file of the test directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestEnvString tests that the first set environment variable is used, else the default value
+func TestEnvString(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary string
+		legacy  string
+		want    string
+	}{
+		{name: "unset", want: "default"},
+		{name: "primary", primary: "a", legacy: "b", want: "a"},
+		{name: "fallback", legacy: "b", want: "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, "NODE_REFINER_TEST_PRIMARY", tt.primary)
+			setenv(t, "NODE_REFINER_TEST_LEGACY", tt.legacy)
+			if got := envString("default", "NODE_REFINER_TEST_PRIMARY", "NODE_REFINER_TEST_LEGACY"); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestEnvDuration tests that an invalid duration is an error like an invalid flag
+func TestEnvDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "unset", want: time.Minute},
+		{name: "set", value: "90s", want: 90 * time.Second},
+		{name: "invalid", value: "ten", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, "NODE_REFINER_TEST_DURATION", tt.value)
+			got, err := envDuration(time.Minute, "NODE_REFINER_TEST_DURATION")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "NODE_REFINER_TEST_DURATION") {
+					t.Errorf("Expected an error naming the variable, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %v, got %v (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
+
+// TestFlagPrecedence tests that the flags take precedence over the environment variables
+func TestFlagPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    string
+		args   []string
+		want   string
+		format string
+	}{
+		{name: "default", want: "info", format: logFormatConsole},
+		{name: "env", env: "debug", want: "debug", format: logFormatConsole},
+		{name: "flag over env", env: "debug", args: []string{"--log-level", "error", "--log-format", "json"}, want: "error", format: logFormatJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, "NODE_REFINER_LOG_LEVEL", tt.env)
+			setenv(t, "NODE_REFINER_LOG_FORMAT", "")
+			var lf logFlags
+			fs := newFlagSet("test")
+			lf.bind(fs, "info")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if lf.logLevel != tt.want || lf.logFormat != tt.format {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.want, tt.format, lf.logLevel, lf.logFormat)
+			}
+		})
+	}
+}
+
+// TestNewLogger tests the parsing of the log level and format
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		level   string
+		format  string
+		wantErr string
+	}{
+		{level: "debug", format: logFormatConsole},
+		{level: "warn", format: logFormatJSON},
+		{level: "verbose", format: logFormatJSON, wantErr: "unknown log level"},
+		{level: "info", format: "text", wantErr: "unknown log format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level+"/"+tt.format, func(t *testing.T) {
+			logger, err := newLogger(tt.level, tt.format)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if logger.Core().Enabled(zapcore.DebugLevel) != (tt.level == "debug") || !logger.Core().Enabled(zapcore.WarnLevel) {
+				t.Errorf("Unexpected levels enabled for %s", tt.level)
+			}
+		})
+	}
+}
+
+// TestExecute tests that the subcommands are dispatched and their errors turned into exit codes
+func TestExecute(t *testing.T) {
+	var called string
+	var calledArgs []string
+	stub := func(name string, err error) func(args []string) error {
+		return func(args []string) error {
+			called, calledArgs = name, args
+			return err
+		}
+	}
+	saved := commands
+	t.Cleanup(func() { commands = saved })
+	commands = []command{
+		{name: "run", run: stub("run", nil)},
+		{name: "report", run: stub("report", errors.New("unreachable"))},
+		{name: "version", run: stub("version", flag.ErrHelp)},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		wantArgs int
+		code     int
+	}{
+		{name: "default", args: nil, want: "run", code: 0},
+		{name: "default with flags", args: []string{"--log-level", "debug"}, want: "run", wantArgs: 2, code: 0},
+		{name: "subcommand", args: []string{"report", "-o", "json"}, want: "report", wantArgs: 2, code: 1},
+		{name: "help flag", args: []string{"version", "-h"}, want: "version", wantArgs: 1, code: 0},
+		{name: "help", args: []string{"help"}, code: 0},
+		{name: "unknown", args: []string{"drain"}, code: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called, calledArgs = "", nil
+			if code := Execute(tt.args); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d", tt.code, code)
+			}
+			if called != tt.want || len(calledArgs) != tt.wantArgs {
+				t.Errorf("Expected %q with %d arguments, got %q with %v", tt.want, tt.wantArgs, called, calledArgs)
+			}
+		})
+	}
+}
+
+// TestRunInvalidValues tests that invalid flags and environment variables fail before the controller starts
+func TestRunInvalidValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		args    []string
+		wantErr string
+	}{
+		{name: "loop interval env", env: "ten", wantErr: "NODE_REFINER_LOOP_INTERVAL"},
+		{name: "loop interval", args: []string{"--loop-interval", "0s"}, wantErr: "loop interval must be positive"},
+		{name: "unknown flag", args: []string{"--metrics-port", "8080"}, wantErr: "metrics-port"},
+		{name: "arguments", args: []string{"extra"}, wantErr: "unexpected arguments"},
+		{name: "sample ratio", args: []string{"--trace-sample-ratio", "2"}, wantErr: "--trace-sample-ratio"},
+		{name: "audit config map", args: []string{"--audit-config-map", "audit"}, wantErr: "expected namespace/name"},
+		{name: "admin auth", args: []string{"--admin-token-file", "token", "--admin-kubernetes-auth"}, wantErr: "mutually exclusive"},
+		{name: "tls", args: []string{"--tls-cert-file", "tls.crt"}, wantErr: "--tls-key-file"},
+		{name: "priority threshold", args: []string{"--drain-priority-threshold", "high"}, wantErr: "invalid drain priority threshold"},
+		{name: "size", args: []string{"--record-max-file-size", "big"}, wantErr: "invalid size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, "NODE_REFINER_LOOP_INTERVAL", tt.env)
+			err := runCommand(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Supported log formats
+const (
+	logFormatConsole = "console"
+	logFormatJSON    = "json"
+)
+
+// newLogger builds a zap logger for the given level and format. The console format
+// is meant for development, the json format for production
+func newLogger(level, format string) (*zap.Logger, error) {
+	var config zap.Config
+	switch format {
+	case logFormatConsole:
+		config = zap.NewDevelopmentConfig()
+	case logFormatJSON:
+		config = zap.NewProductionConfig()
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected %q or %q", format, logFormatConsole, logFormatJSON)
+	}
+
+	var zapLevel zapcore.Level
+	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q: %v", level, err)
+	}
+	config.Level = zap.NewAtomicLevelAt(zapLevel)
+
+	return config.Build()
+}
+
+// setupLogger replaces the global zap logger and returns a function flushing it
+func setupLogger(level, format string) (func(), error) {
+	logger, err := newLogger(level, format)
+	if err != nil {
+		return nil, err
+	}
+	zap.ReplaceGlobals(logger)
+	zap.S().Debug("Setting up zap as global logger")
+
+	return func() {
+		// Syncing stderr/stdout can fail on some platforms, nothing to be done about it
+		_ = logger.Sync()
+	}, nil
+}
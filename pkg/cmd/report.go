@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/common"
+	"github.com/SAP/node-refiner/pkg/controller"
+	"github.com/SAP/node-refiner/pkg/drainer"
+	"github.com/SAP/node-refiner/pkg/snapshot"
+	"github.com/SAP/node-refiner/pkg/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// reportFlags are the flags of the report command
+type reportFlags struct {
+	clusterFlags
+	configMapName      string
+	configMapNamespace string
+	showPods           bool
+	fromFile           string
+	priceTable         string
+	exclusions         exclusionFlags
+	output             types.OutputFormat
+}
+
+// reportDocument is the single document printed by the report command in structured formats
+type reportDocument struct {
+	Cluster   types.ClusterReport `json:"cluster"`
+	Nodes     []types.NodeReport  `json:"nodes"`
+	Pods      []types.PodReport   `json:"pods,omitempty"`
+	Candidate string              `json:"candidate,omitempty"`
+	Decision  decisionReport      `json:"decision"`
+}
+
+// decisionReport explains why the drainer would or wouldn't act
+type decisionReport struct {
+	Drain  bool   `json:"drain"`
+	Reason string `json:"reason"`
+}
+
+func reportCommand(args []string) error {
+	var rf reportFlags
+
+	fs := newFlagSet("report")
+	rf.bind(fs, "error")
+	fs.StringVar(&rf.configMapName, "config-map", envString(controller.DefaultConfigMapName, "NODE_REFINER_CONFIG_MAP"),
+		"Name of the ConfigMap holding the drainer settings ($NODE_REFINER_CONFIG_MAP)")
+	fs.StringVar(&rf.configMapNamespace, "config-map-namespace", envString(controller.DefaultConfigMapNamespace, "NODE_REFINER_CONFIG_MAP_NAMESPACE"),
+		"Namespace of the settings ConfigMap, all namespaces are searched when empty ($NODE_REFINER_CONFIG_MAP_NAMESPACE)")
+	fs.BoolVar(&rf.showPods, "pods", false, "Also print the table of pods")
+	fs.StringVar(&rf.fromFile, "from-file", "",
+		"Analyse a dump instead of a live cluster: the output of \"kubectl get nodes,pods -A -o json|yaml\" or \"kubectl cluster-info dump\" (file or --output-directory)")
+	fs.StringVar(&rf.priceTable, "price-table", envString("", "NODE_REFINER_PRICE_TABLE"),
+		"File of the hourly prices of the nodes by instance type or pool, to estimate the costs ($NODE_REFINER_PRICE_TABLE)")
+	rf.exclusions.bind(fs)
+	rf.output = types.OutputTable
+	fs.Var(&rf.output, "output", fmt.Sprintf("Output format, one of %v", types.OutputFormats))
+	fs.Var(&rf.output, "o", "Shorthand for --output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	flush, err := setupLogger(rf.logLevel, rf.logFormat)
+	if err != nil {
+		return err
+	}
+	defer flush()
+
+	var prices *types.PriceTable
+	if rf.priceTable != "" {
+		if prices, err = types.LoadPriceTable(rf.priceTable); err != nil {
+			return err
+		}
+	}
+
+	exclusions, err := rf.exclusions.load()
+	if err != nil {
+		return err
+	}
+
+	var snap *snapshot.Snapshot
+	var cm *corev1.ConfigMap
+	if rf.fromFile != "" {
+		if snap, err = snapshot.LoadDump(rf.fromFile); err != nil {
+			return err
+		}
+		if cm, err = snap.ConfigMap(rf.configMapName, rf.configMapNamespace); err != nil {
+			return err
+		}
+	} else {
+		client, err := common.GetClientFor(rf.kubeconfig, rf.context)
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		if snap, err = snapshot.FromCluster(ctx, client); err != nil {
+			return err
+		}
+		if cm, err = getSettingsConfigMap(ctx, client, rf.configMapName, rf.configMapNamespace); err != nil {
+			return err
+		}
+	}
+
+	// The drainer is only asked for its decision, it never needs a client
+	d := drainer.NewAPICordonDrainer(nil, nil)
+	if cm != nil {
+		if err := d.UpdateSettings(cm); err != nil {
+			return errors.Wrapf(err, "invalid settings in ConfigMap %s/%s", cm.Namespace, cm.Name)
+		}
+	} else {
+		zap.S().Warnw("Settings ConfigMap not found, using the default drainer settings", "name", rf.configMapName, "namespace", rf.configMapNamespace)
+	}
+	d.SetLastNodeAddition(snap.LastNodeAddition())
+	d.SetLastNodeReady(snap.LastNodeReady())
+	for _, created := range snap.PodCreations(map[string]bool{}) {
+		d.RecordPodChurn(created)
+	}
+
+	nodesMap, podsMap := snap.Maps()
+	analysis := controller.AnalyzeContext(context.Background(), nodesMap, podsMap, exclusions)
+	analysis.EstimateCost(prices)
+
+	return writeReport(os.Stdout, rf.output, &analysis, d, snap.Time, podsMap, rf.showPods)
+}
+
+// writeReport prints the analysis and the drainer decision at the time of the snapshot,
+// structured formats are written as a single document
+func writeReport(w io.Writer, format types.OutputFormat, analysis *controller.Analysis, d *drainer.APICordonDrainer,
+	at time.Time, podsMap map[string]types.PodManifest, showPods bool) error {
+	candidate := ""
+	if analysis.Candidate != nil {
+		candidate = analysis.Candidate.Node.Name
+	}
+	decision := decide(analysis, d, at)
+
+	if format.IsStructured() {
+		doc := reportDocument{
+			Cluster:   types.NewClusterReport(&analysis.Cluster),
+			Nodes:     types.NewNodeReports(analysis.Nodes, candidate),
+			Candidate: candidate,
+			Decision:  decision,
+		}
+		if showPods {
+			doc.Pods = types.NewPodReports(podsMap)
+		}
+		return types.WriteStructured(w, format, doc)
+	}
+
+	if err := types.TabulateCluster(w, format, &analysis.Cluster); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	if err := types.TabulateNodeMap(w, format, analysis.Nodes, candidate); err != nil {
+		return err
+	}
+	if showPods {
+		fmt.Fprintln(w)
+		if err := types.TabulatePodsMap(w, format, podsMap); err != nil {
+			return err
+		}
+	}
+	if format == types.OutputCSV {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	if analysis.Candidate == nil {
+		fmt.Fprintf(w, "Drain candidate: none\n")
+	} else {
+		nm := analysis.Candidate
+		fmt.Fprintf(w, "Drain candidate: %s (%d pods, score %.2f, CPU %s, RAM %s",
+			nm.Node.Name, len(nm.Pods), nm.Utilization.Score,
+			common.FormatPercentage(nm.Utilization.PercentageCPU), common.FormatPercentage(nm.Utilization.PercentageRAM))
+		if nm.Priced {
+			fmt.Fprintf(w, ", %s per hour", types.FormatCurrency(nm.CostPerHour, analysis.Cluster.Currency))
+		}
+		fmt.Fprintln(w, ")")
+	}
+	if decision.Drain {
+		fmt.Fprintf(w, "Drainer decision: would drain %s (%s)\n", candidate, decision.Reason)
+	} else {
+		fmt.Fprintf(w, "Drainer decision: would not drain (%s)\n", decision.Reason)
+	}
+	return nil
+}
+
+// getSettingsConfigMap looks up the settings ConfigMap, returns nil if it does not exist
+func getSettingsConfigMap(ctx context.Context, client kubernetes.Interface, name, namespace string) (*corev1.ConfigMap, error) {
+	cms, err := client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get the settings ConfigMap")
+	}
+	switch len(cms.Items) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &cms.Items[0], nil
+	default:
+		return nil, errors.Errorf("found %d ConfigMaps named %s, please set the namespace", len(cms.Items), name)
+	}
+}
+
+// decide evaluates whether the drainer would act on the analysis at the given time, the time of
+// the snapshot, so that a dump is judged by the cooldowns and the schedule of when it was taken
+func decide(analysis *controller.Analysis, d *drainer.APICordonDrainer, at time.Time) decisionReport {
+	if analysis.Candidate == nil {
+		return decisionReport{Reason: analysis.CandidateErr.Error()}
+	}
+	decision := d.EvaluateDrainAt(at, &analysis.Cluster)
+	return decisionReport{Drain: decision.Allowed, Reason: decision.Reason}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testDump is a cluster of three nodes, node-c is empty
+const testDump = `{
+    "kind": "List",
+    "apiVersion": "v1",
+    "items": [
+        {"kind": "Node", "apiVersion": "v1", "metadata": {"name": "node-a", "creationTimestamp": "2022-08-01T00:00:00Z"},
+         "status": {"allocatable": {"cpu": "4", "memory": "8Gi"},
+                    "conditions": [{"type": "Ready", "status": "True", "lastTransitionTime": "2022-08-01T00:00:00Z"}]}},
+        {"kind": "Node", "apiVersion": "v1", "metadata": {"name": "node-b", "creationTimestamp": "2022-08-01T00:00:00Z"},
+         "status": {"allocatable": {"cpu": "4", "memory": "8Gi"},
+                    "conditions": [{"type": "Ready", "status": "True", "lastTransitionTime": "2022-08-01T00:00:00Z"}]}},
+        {"kind": "Node", "apiVersion": "v1", "metadata": {"name": "node-c", "creationTimestamp": "2022-08-01T00:00:00Z"},
+         "status": {"allocatable": {"cpu": "4", "memory": "8Gi"},
+                    "conditions": [{"type": "Ready", "status": "True", "lastTransitionTime": "2022-08-01T00:00:00Z"}]}},
+        {"kind": "Pod", "apiVersion": "v1", "metadata": {"name": "web-1", "namespace": "default", "creationTimestamp": "2022-08-01T00:00:00Z"},
+         "spec": {"nodeName": "node-a", "containers": [{"name": "web", "resources": {"requests": {"cpu": "1", "memory": "2Gi"}}}]},
+         "status": {"phase": "Running"}},
+        {"kind": "Pod", "apiVersion": "v1", "metadata": {"name": "web-2", "namespace": "default", "creationTimestamp": "2022-08-01T00:00:00Z"},
+         "spec": {"nodeName": "node-b", "containers": [{"name": "web", "resources": {"requests": {"cpu": "1", "memory": "2Gi"}}}]},
+         "status": {"phase": "Running"}},
+        {"kind": "ConfigMap", "apiVersion": "v1", "metadata": {"name": "node-refiner-cm", "namespace": "node-refiner"},
+         "data": {"drainer_enabled": "false"}}
+    ]
+}`
+
+// TestReportFromFile tests the report of a dump in the table and structured formats
+func TestReportFromFile(t *testing.T) {
+	dump := writeFile(t, "dump.json", testDump)
+
+	out, err := captureStdout(t, func() error { return reportCommand([]string{"--from-file", dump, "-o", "json"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc reportDocument
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("Invalid report %q: %v", out, err)
+	}
+	if doc.Cluster.Nodes != 3 || doc.Cluster.Pods != 2 || len(doc.Nodes) != 3 {
+		t.Errorf("Expected 3 nodes and 2 pods, got %+v", doc.Cluster)
+	}
+	if doc.Candidate != "node-c" {
+		t.Errorf("Expected the empty node to be the candidate, got %q", doc.Candidate)
+	}
+	if doc.Decision.Drain || !strings.Contains(doc.Decision.Reason, "disabled") {
+		t.Errorf("Expected no drain with the drainer disabled by the ConfigMap, got %+v", doc.Decision)
+	}
+
+	out, err = captureStdout(t, func() error { return reportCommand([]string{"--from-file", dump, "--pods"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"node-a", "node-c", "web-2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %s in the report:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/audit"
+	"github.com/SAP/node-refiner/pkg/controller"
+	"github.com/SAP/node-refiner/pkg/tracing"
+	"github.com/SAP/node-refiner/pkg/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// logFlags are the flags shared by every command
+type logFlags struct {
+	logLevel  string
+	logFormat string
+}
+
+// clusterFlags are the flags shared by every command talking to a cluster
+type clusterFlags struct {
+	logFlags
+	kubeconfig string
+	context    string
+}
+
+// bind registers the flags, logLevel is the default log level of the command
+func (f *clusterFlags) bind(fs *flag.FlagSet, logLevel string) {
+	fs.StringVar(&f.kubeconfig, "kubeconfig", "",
+		"Path to the kubeconfig file, when empty the in-cluster config is used inside a pod and $KUBECONFIG or ~/.kube/config outside")
+	fs.StringVar(&f.context, "context", envString("", "NODE_REFINER_CONTEXT"),
+		"Kubeconfig context to use, defaults to the current context ($NODE_REFINER_CONTEXT)")
+	f.logFlags.bind(fs, logLevel)
+}
+
+// bind registers the flags, logLevel is the default log level of the command
+func (f *logFlags) bind(fs *flag.FlagSet, logLevel string) {
+	fs.StringVar(&f.logLevel, "log-level", envString(logLevel, "NODE_REFINER_LOG_LEVEL"),
+		"Log level: debug, info, warn or error ($NODE_REFINER_LOG_LEVEL)")
+	fs.StringVar(&f.logFormat, "log-format", envString(logFormatConsole, "NODE_REFINER_LOG_FORMAT"),
+		"Log format: console for development or json for production ($NODE_REFINER_LOG_FORMAT)")
+}
+
+// exclusionFlags are the flags selecting the pods left out of the utilization or keeping their
+// nodes from being drained
+type exclusionFlags struct {
+	file              string
+	priorityThreshold string
+}
+
+func (f *exclusionFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.file, "exclusions", envString("", "NODE_REFINER_EXCLUSIONS"),
+		"File of the namespaces, pod selectors and priority threshold of the pods left out of the utilization or keeping their nodes from being drained ($NODE_REFINER_EXCLUSIONS)")
+	fs.StringVar(&f.priorityThreshold, "drain-priority-threshold", envString("", "NODE_REFINER_DRAIN_PRIORITY_THRESHOLD"),
+		"Priority from which pods keep their nodes from being drained, overrides the threshold of the exclusions file ($NODE_REFINER_DRAIN_PRIORITY_THRESHOLD)")
+}
+
+// threshold parses the priority threshold, nil when not set
+func (f *exclusionFlags) threshold() (*int32, error) {
+	if f.priorityThreshold == "" {
+		return nil, nil
+	}
+	threshold, err := strconv.ParseInt(f.priorityThreshold, 10, 32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid drain priority threshold %q", f.priorityThreshold)
+	}
+	value := int32(threshold)
+	return &value, nil
+}
+
+// load reads the exclusions file and applies the priority threshold, nil when neither is set
+func (f *exclusionFlags) load() (*types.Exclusions, error) {
+	threshold, err := f.threshold()
+	if err != nil {
+		return nil, err
+	}
+	var exclusions *types.Exclusions
+	if f.file != "" {
+		if exclusions, err = types.LoadExclusions(f.file); err != nil {
+			return nil, err
+		}
+	}
+	return exclusions.WithPriorityThreshold(threshold), nil
+}
+
+func runCommand(args []string) error {
+	var cf clusterFlags
+	var ef exclusionFlags
+	opts := controller.DefaultOptions()
+	tracingOpts := tracing.Options{SampleRatio: 1}
+
+	loopInterval, err := envDuration(opts.LoopInterval, "NODE_REFINER_LOOP_INTERVAL")
+	if err != nil {
+		return err
+	}
+
+	fs := newFlagSet("run")
+	cf.bind(fs, "info")
+	fs.StringVar(&opts.Server.Address, "listen-address", envString(opts.Server.Address, "NODE_REFINER_LISTEN_ADDRESS"),
+		"Address serving the prometheus metrics and the health endpoints ($NODE_REFINER_LISTEN_ADDRESS)")
+	fs.StringVar(&opts.Server.TLSCertFile, "tls-cert-file", envString("", "NODE_REFINER_TLS_CERT_FILE"),
+		"Certificate to serve TLS with, requires --tls-key-file ($NODE_REFINER_TLS_CERT_FILE)")
+	fs.StringVar(&opts.Server.TLSKeyFile, "tls-key-file", envString("", "NODE_REFINER_TLS_KEY_FILE"),
+		"Private key of the TLS certificate ($NODE_REFINER_TLS_KEY_FILE)")
+	fs.DurationVar(&opts.Server.ReadTimeout, "http-read-timeout", opts.Server.ReadTimeout, "Time to read a request, headers included")
+	fs.DurationVar(&opts.Server.WriteTimeout, "http-write-timeout", opts.Server.WriteTimeout, "Time to write a response")
+	fs.DurationVar(&opts.Server.IdleTimeout, "http-idle-timeout", opts.Server.IdleTimeout, "Time an idle keep-alive connection is kept open")
+	fs.StringVar(&opts.MetricsPrefix, "metrics-prefix", envString(opts.MetricsPrefix, "NODE_REFINER_METRICS_PREFIX"),
+		"Prefix of the exported prometheus metrics ($NODE_REFINER_METRICS_PREFIX)")
+	fs.StringVar(&opts.ConfigMapName, "config-map", envString(opts.ConfigMapName, "NODE_REFINER_CONFIG_MAP"),
+		"Name of the ConfigMap holding the drainer settings ($NODE_REFINER_CONFIG_MAP)")
+	fs.StringVar(&opts.ConfigMapNamespace, "config-map-namespace", envString(opts.ConfigMapNamespace, "NODE_REFINER_CONFIG_MAP_NAMESPACE"),
+		"Namespace of the settings ConfigMap, all namespaces are watched when empty ($NODE_REFINER_CONFIG_MAP_NAMESPACE)")
+	fs.DurationVar(&opts.LoopInterval, "loop-interval", loopInterval,
+		"Time between two runs of the calculation loop ($NODE_REFINER_LOOP_INTERVAL)")
+	fs.IntVar(&opts.HistorySize, "history-size", opts.HistorySize, "Number of drain decisions served on /api/v1/history")
+	fs.StringVar(&opts.AdminTokenFile, "admin-token-file", envString("", "NODE_REFINER_ADMIN_TOKEN_FILE"),
+		"File holding the bearer token of the admin API ($NODE_REFINER_ADMIN_TOKEN_FILE)")
+	fs.BoolVar(&opts.AdminKubernetesAuth, "admin-kubernetes-auth", false,
+		"Authenticate and authorize the admin API requests with Kubernetes TokenReviews and SubjectAccessReviews")
+	fs.StringVar(&opts.NotificationConfig, "notification-config", envString("", "NODE_REFINER_NOTIFICATION_CONFIG"),
+		"File listing the webhooks told when scale downs start, succeed and fail, disabled when empty ($NODE_REFINER_NOTIFICATION_CONFIG)")
+	fs.StringVar(&opts.PriceTable, "price-table", envString("", "NODE_REFINER_PRICE_TABLE"),
+		"File of the hourly prices of the nodes by instance type or pool, costs are not estimated when empty ($NODE_REFINER_PRICE_TABLE)")
+	ef.bind(fs)
+	fs.StringVar(&opts.Audit.File, "audit-file", envString("", "NODE_REFINER_AUDIT_FILE"),
+		"File the decisions and actions are appended to as JSON lines ($NODE_REFINER_AUDIT_FILE)")
+	fs.StringVar(&opts.Audit.ConfigMap, "audit-config-map", envString("", "NODE_REFINER_AUDIT_CONFIG_MAP"),
+		"ConfigMap, as namespace/name, keeping the latest decisions and actions ($NODE_REFINER_AUDIT_CONFIG_MAP)")
+	fs.IntVar(&opts.Audit.ConfigMapSize, "audit-config-map-size", opts.Audit.ConfigMapSize, "Number of records kept in the audit ConfigMap")
+	fs.BoolVar(&opts.Audit.Stdout, "audit-stdout", false, "Write the decisions and actions to stdout as JSON lines")
+	fs.StringVar(&tracingOpts.Endpoint, "otlp-endpoint", envString("", "NODE_REFINER_OTLP_ENDPOINT"),
+		"OTLP/HTTP collector, as host:port, the calculation loop and the scale downs are traced to, disabled when empty ($NODE_REFINER_OTLP_ENDPOINT)")
+	fs.BoolVar(&tracingOpts.Insecure, "otlp-insecure", false, "Send the traces to the collector over HTTP instead of HTTPS")
+	fs.Float64Var(&tracingOpts.SampleRatio, "trace-sample-ratio", tracingOpts.SampleRatio, "Fraction of the calculation loops and scale downs traced, from 0 to 1")
+	fs.StringVar(&opts.Recorder.Dir, "record-dir", envString("", "NODE_REFINER_RECORD_DIR"),
+		"Directory to record every iteration of the calculation loop to, disabled when empty ($NODE_REFINER_RECORD_DIR)")
+	fs.Var(newSizeFlag(&opts.Recorder.MaxFileSize), "record-max-file-size", "Compressed size after which a new history file is started")
+	fs.DurationVar(&opts.Recorder.MaxFileAge, "record-max-file-age", opts.Recorder.MaxFileAge, "Time after which a new history file is started")
+	fs.DurationVar(&opts.Recorder.MaxAge, "record-max-age", opts.Recorder.MaxAge, "Time after which a history file is deleted")
+	fs.Var(newSizeFlag(&opts.Recorder.MaxTotalSize), "record-max-total-size", "Total size of the history files, the oldest are deleted beyond it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.Errorf("unexpected arguments %v", fs.Args())
+	}
+	if opts.LoopInterval <= 0 {
+		return errors.New("loop interval must be positive")
+	}
+	if tracingOpts.SampleRatio < 0 || tracingOpts.SampleRatio > 1 {
+		return errors.New("--trace-sample-ratio must be between 0 and 1")
+	}
+	if opts.Audit.ConfigMap != "" {
+		if _, _, err := audit.ParseConfigMapRef(opts.Audit.ConfigMap); err != nil {
+			return err
+		}
+	}
+	if opts.AdminTokenFile != "" && opts.AdminKubernetesAuth {
+		return errors.New("--admin-token-file and --admin-kubernetes-auth are mutually exclusive")
+	}
+	if (opts.Server.TLSCertFile == "") != (opts.Server.TLSKeyFile == "") {
+		return errors.New("--tls-cert-file and --tls-key-file must be set together")
+	}
+	opts.Exclusions = ef.file
+	if opts.DrainPriorityThreshold, err = ef.threshold(); err != nil {
+		return err
+	}
+	opts.Kubeconfig = cf.kubeconfig
+	opts.Context = cf.context
+
+	flush, err := setupLogger(cf.logLevel, cf.logFormat)
+	if err != nil {
+		return err
+	}
+	defer flush()
+
+	zap.S().Infow("Starting Node Refiner", "version", Version, "commit", Commit)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingOpts)
+	if err != nil {
+		return errors.Wrap(err, "unable to set up tracing")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			zap.S().Warnw("Unable to flush the traces", "error", err)
+		}
+	}()
+
+	c, err := controller.NewController(opts)
+	if err != nil {
+		return errors.Wrap(err, "unable to instantiate the controller")
+	}
+	go c.RunCalculationLoop()
+
+	return c.CreateRunInformers()
+}
+
+// sizeFlag is a size in bytes set as a quantity, e.g. 64Mi or 1G
+type sizeFlag struct {
+	bytes *int64
+}
+
+func newSizeFlag(bytes *int64) *sizeFlag {
+	return &sizeFlag{bytes: bytes}
+}
+
+func (f *sizeFlag) String() string {
+	if f.bytes == nil {
+		return ""
+	}
+	return resource.NewQuantity(*f.bytes, resource.BinarySI).String()
+}
+
+func (f *sizeFlag) Set(value string) error {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return fmt.Errorf("invalid size %q: %v", value, err)
+	}
+	*f.bytes = q.Value()
+	return nil
+}
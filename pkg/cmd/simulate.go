@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/simulator"
+	"github.com/SAP/node-refiner/pkg/snapshot"
+	"github.com/SAP/node-refiner/pkg/types"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// settingsFlag collects repeated key=value drainer settings
+type settingsFlag map[string]string
+
+func (s settingsFlag) String() string {
+	pairs := make([]string, 0, len(s))
+	for key, value := range s {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (s settingsFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return errors.Errorf("expected key=value, got %q", value)
+	}
+	s[parts[0]] = parts[1]
+	return nil
+}
+
+func simulateCommand(args []string) error {
+	var lf logFlags
+	var ef exclusionFlags
+	var settingsFile string
+	overrides := settingsFlag{}
+	output := types.OutputTable
+
+	fs := newFlagSet("simulate")
+	lf.bind(fs, "error")
+	fs.StringVar(&settingsFile, "settings", "", "ConfigMap manifest (YAML or JSON) holding the drainer settings to simulate")
+	fs.Var(overrides, "set", "Drainer setting as key=value, e.g. excess_nodes_threshold=1, overrides --settings (repeatable)")
+	ef.bind(fs)
+	fs.Var(&output, "output", fmt.Sprintf("Output format, one of %v", types.OutputFormats))
+	fs.Var(&output, "o", "Shorthand for --output")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: node-refiner simulate [flags] <history or dump>...\n\n"+
+			"Replays history files recorded by node-refiner and cluster dumps through the drainer checks.\n"+
+			"The drainer is enabled unless drainer_enabled is set.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("at least one history file or dump is required")
+	}
+
+	flush, err := setupLogger(lf.logLevel, lf.logFormat)
+	if err != nil {
+		return err
+	}
+	defer flush()
+
+	settings, err := loadSettings(settingsFile, overrides)
+	if err != nil {
+		return err
+	}
+	exclusions, err := ef.load()
+	if err != nil {
+		return err
+	}
+	history, err := snapshot.Load(fs.Args()...)
+	if err != nil {
+		return err
+	}
+	result, err := simulator.Run(history, settings, exclusions)
+	if err != nil {
+		return err
+	}
+
+	return writeSimulation(os.Stdout, output, result)
+}
+
+// loadSettings merges the settings file and the overrides into a settings ConfigMap
+func loadSettings(path string, overrides settingsFlag) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{Data: map[string]string{"drainer_enabled": "true"}}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fromFile corev1.ConfigMap
+		if err := yaml.Unmarshal(data, &fromFile); err != nil {
+			return nil, errors.Wrapf(err, "cannot read settings %s", path)
+		}
+		for key, value := range fromFile.Data {
+			cm.Data[key] = value
+		}
+	}
+	for key, value := range overrides {
+		cm.Data[key] = value
+	}
+	return cm, nil
+}
+
+// writeSimulation prints the timeline of the simulation and its summary
+func writeSimulation(w io.Writer, format types.OutputFormat, result *simulator.Result) error {
+	if format.IsStructured() {
+		return types.WriteStructured(w, format, result)
+	}
+
+	rows := make([][]string, 0, len(result.Steps))
+	for _, step := range result.Steps {
+		rows = append(rows, []string{
+			step.Time.Format(time.RFC3339),
+			fmt.Sprint(step.RecordedNodes),
+			fmt.Sprint(step.Nodes),
+			fmt.Sprintf("%.2f", step.ExcessNodes),
+			step.Candidate,
+			types.YesNo(step.Drained),
+			step.Reason,
+		})
+	}
+	if err := types.WriteRows(w, format, "Simulated Timeline",
+		[]string{"Time", "Recorded Nodes", "Nodes", "Excess Nodes", "Candidate", "Drained", "Reason"}, rows); err != nil {
+		return err
+	}
+	if format == types.OutputCSV {
+		return nil
+	}
+
+	fmt.Fprintf(w, "\nDrains: %d", result.Drains)
+	if result.Drains > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(result.DrainedNodes, ", "))
+	}
+	fmt.Fprintln(w)
+	return nil
+}
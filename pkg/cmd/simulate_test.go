@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/SAP/node-refiner/pkg/simulator"
+)
+
+// TestSimulate tests that the settings file and the overrides are applied to the simulation of a dump
+func TestSimulate(t *testing.T) {
+	dump := writeFile(t, "dump.json", testDump)
+	disabled := writeFile(t, "settings.yaml", "apiVersion: v1\nkind: ConfigMap\ndata:\n  drainer_enabled: \"false\"\n")
+
+	tests := []struct {
+		name    string
+		args    []string
+		drained []string
+		wantErr string
+	}{
+		{name: "default", args: []string{dump}, drained: []string{"node-c"}},
+		{name: "settings file", args: []string{"--settings", disabled, dump}},
+		{name: "override", args: []string{"--settings", disabled, "--set", "drainer_enabled=true", dump}, drained: []string{"node-c"}},
+		{name: "priority threshold", args: []string{"--drain-priority-threshold", "high", dump}, wantErr: "invalid drain priority threshold"},
+		{name: "no dump", wantErr: "at least one history file or dump"},
+		{name: "invalid override", args: []string{"--set", "drainer_enabled", dump}, wantErr: "key=value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := captureStdout(t, func() error { return simulateCommand(append([]string{"-o", "json"}, tt.args...)) })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result simulator.Result
+			if err := json.Unmarshal([]byte(out), &result); err != nil {
+				t.Fatalf("Invalid simulation %q: %v", out, err)
+			}
+			if len(result.Steps) != 1 || result.Drains != len(tt.drained) || strings.Join(result.DrainedNodes, ",") != strings.Join(tt.drained, ",") {
+				t.Errorf("Expected %v drained, got %+v", tt.drained, result)
+			}
+		})
+	}
+}
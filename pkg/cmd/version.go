@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Build information, set at build time through
+// -ldflags "-X github.com/SAP/node-refiner/pkg/cmd.Version=... -X github.com/SAP/node-refiner/pkg/cmd.Commit=..."
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+func versionCommand(args []string) error {
+	fs := newFlagSet("version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	fmt.Printf("node-refiner %s (commit %s, %s %s/%s)\n", Version, Commit, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
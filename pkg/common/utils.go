@@ -8,57 +8,89 @@ import (
 	"os/signal"
 	"syscall"
 
-	"go.uber.org/zap"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+var (
+	// ErrKubeConfig is returned when no usable kubernetes configuration could be loaded
+	ErrKubeConfig = errors.New("cannot get kubernetes config")
+	// ErrKubeClient is returned when a clientset could not be built from a loaded configuration
+	ErrKubeClient = errors.New("cannot create kubernetes client")
+)
+
 // GetClient returns a k8s clientset to the request from inside of cluster
 func GetClient() (kubernetes.Interface, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
-		zap.S().Fatalf("Can not get kubernetes config: %v", err)
-		return nil, err
-	}
-
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		zap.S().Fatalf("Can not create kubernetes client: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrKubeConfig, err)
 	}
 
-	return clientset, nil
+	return newClientset(config)
 }
 
-func buildOutOfClusterConfig() (*rest.Config, error) {
-	//kubeconfigPath := "/Users/ali/Dev/Kubernetes/kubeconfig/ber-config.yaml"
-	kubeconfigPath := os.Getenv("KUBECONFIG")
-	if kubeconfigPath == "" {
-		kubeconfigPath = os.Getenv("HOME") + "/.kube/config"
+// GetClientFor returns a k8s clientset using the in-cluster config when running inside a pod
+// and no kubeconfig or context was requested, or the kubeconfig otherwise
+func GetClientFor(kubeconfigPath, kubeContext string) (kubernetes.Interface, error) {
+	if kubeconfigPath != "" || kubeContext != "" {
+		return GetClientFromKubeconfig(kubeconfigPath, kubeContext)
+	}
+	if _, err := rest.InClusterConfig(); err != nil {
+		return GetClientOutOfCluster()
 	}
-	return clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	return GetClient()
 }
 
 // GetClientOutOfCluster returns a k8s clientset to the request from outside of cluster
 func GetClientOutOfCluster() (kubernetes.Interface, error) {
-	config, err := buildOutOfClusterConfig()
+	return GetClientFromKubeconfig("", "")
+}
+
+// GetClientFromKubeconfig returns a k8s clientset built from the given kubeconfig file and context.
+// An empty path falls back to $KUBECONFIG and then $HOME/.kube/config, an empty context
+// uses the current context of the kubeconfig
+func GetClientFromKubeconfig(kubeconfigPath, kubeContext string) (kubernetes.Interface, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfigPath
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
 	if err != nil {
-		zap.S().Fatalf("Can not get kubernetes config: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrKubeConfig, err)
 	}
 
+	return newClientset(config)
+}
+
+func newClientset(config *rest.Config) (kubernetes.Interface, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		zap.S().Fatalf("Can not create kubernetes client: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrKubeClient, err)
 	}
 
 	return clientset, nil
 }
 
+// IsTransientAPIError reports whether an error returned by the API server is
+// likely to go away on its own and the request is therefore worth retrying
+func IsTransientAPIError(err error) bool {
+	return apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) ||
+		utilnet.IsConnectionRefused(err) ||
+		utilnet.IsConnectionReset(err) ||
+		utilnet.IsProbableEOF(err)
+}
+
 // FormatValue to prepare the quantities for logging
 func FormatValue(resourceType string, quantity resource.Quantity) string {
 	switch resourceType {
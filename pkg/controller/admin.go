@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/SAP/node-refiner/pkg/audit"
+	"github.com/SAP/node-refiner/pkg/common"
+	"github.com/SAP/node-refiner/pkg/drainer"
+	"github.com/SAP/node-refiner/pkg/supervisor"
+	"github.com/SAP/node-refiner/pkg/types"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
+)
+
+// newAdminAuthenticator returns how the admin API authenticates its requests, nil when the
+// admin API is disabled
+func newAdminAuthenticator(opts Options, client kubernetes.Interface) (supervisor.Authenticator, error) {
+	switch {
+	case opts.AdminTokenFile != "":
+		token, err := ioutil.ReadFile(opts.AdminTokenFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read the admin token")
+		}
+		if len(strings.TrimSpace(string(token))) == 0 {
+			return nil, errors.Errorf("the admin token file %s is empty", opts.AdminTokenFile)
+		}
+		return supervisor.TokenAuthenticator{Token: strings.TrimSpace(string(token))}, nil
+	case opts.AdminKubernetesAuth:
+		return supervisor.KubernetesAuthenticator{Client: client}, nil
+	}
+	return nil, nil
+}
+
+// adminHandler serves the admin API under /api/v1/admin/, the caller authenticates the requests
+func (c *WorkloadsController) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/admin/pause", postOnly(func(res http.ResponseWriter, req *http.Request) {
+		zap.S().Infow("Pausing the drainer", "user", supervisor.User(req.Context()))
+		c.d.Pause()
+		c.auditAdmin("pause", supervisor.User(req.Context()), "", audit.ResultSucceeded, nil)
+		writeJSON(res, http.StatusOK, c.d.Status(c.clock.Now()))
+	}))
+	mux.Handle("/api/v1/admin/resume", postOnly(func(res http.ResponseWriter, req *http.Request) {
+		zap.S().Infow("Resuming the drainer", "user", supervisor.User(req.Context()))
+		c.d.Resume()
+		c.auditAdmin("resume", supervisor.User(req.Context()), "", audit.ResultSucceeded, nil)
+		writeJSON(res, http.StatusOK, c.d.Status(c.clock.Now()))
+	}))
+	mux.Handle("/api/v1/admin/abort", postOnly(func(res http.ResponseWriter, req *http.Request) {
+		zap.S().Infow("Aborting the drains in progress", "user", supervisor.User(req.Context()))
+		nodes, err := c.d.Abort()
+		if !errors.Is(err, drainer.ErrNoDrainInProgress) {
+			c.auditAdmin("abort", supervisor.User(req.Context()), strings.Join(nodes, ","), audit.ResultSucceeded, err)
+		}
+		switch {
+		case errors.Is(err, drainer.ErrNoDrainInProgress):
+			writeJSONError(res, http.StatusConflict, err.Error())
+		case err != nil:
+			writeJSON(res, http.StatusInternalServerError, map[string]interface{}{"aborted": nodes, "error": err.Error()})
+		default:
+			writeJSON(res, http.StatusOK, map[string]interface{}{"aborted": nodes})
+		}
+	}))
+	mux.Handle("/api/v1/admin/drain", postOnly(func(res http.ResponseWriter, req *http.Request) {
+		nodeName := req.URL.Query().Get("node")
+		if nodeName == "" {
+			writeJSONError(res, http.StatusBadRequest, "the node to drain is missing, expected ?node=<name>")
+			return
+		}
+		zap.S().Infow("Drain requested", "node", nodeName, "user", supervisor.User(req.Context()))
+		decision, err := c.requestDrain(nodeName)
+		request, result := "drain", audit.ResultRefused
+		if decision.Reason != "" {
+			request += ": " + decision.Reason
+		}
+		if decision.Allowed {
+			result = audit.ResultAllowed
+		}
+		c.auditAdmin(request, supervisor.User(req.Context()), nodeName, result, err)
+		switch {
+		case errors.Is(err, drainer.ErrNodeNotFound):
+			writeJSONError(res, http.StatusNotFound, err.Error())
+		case decision.Allowed:
+			writeJSON(res, http.StatusAccepted, decision)
+		default:
+			writeJSON(res, http.StatusConflict, decision)
+		}
+	}))
+	return mux
+}
+
+// requestDrain scales the node down if it and the cluster pass the drain checks, the excess
+// nodes are calculated with the requested node instead of the candidate
+func (c *WorkloadsController) requestDrain(nodeName string) (drainer.DrainDecision, error) {
+	analysis := c.analyze(context.Background())
+	nm, ok := analysis.Nodes[nodeName]
+	if !ok {
+		return drainer.DrainDecision{}, fmt.Errorf("%w: %s", drainer.ErrNodeNotFound, nodeName)
+	}
+	if common.CheckForTaints(nm.Node) {
+		return drainer.DrainDecision{Reason: "node is tainted or cordoned"}, nil
+	}
+	if !types.IsNodeReady(nm.Node) {
+		return drainer.DrainDecision{Reason: "node is not ready"}, nil
+	}
+	if nm.DrainBlockedBy != "" {
+		return drainer.DrainDecision{Reason: "node runs " + nm.DrainBlockedBy}, nil
+	}
+
+	analysis.Cluster.CalculateExcessNode(&nm)
+	return c.d.RequestDrain(nodeName, &analysis.Cluster), nil
+}
+
+// postOnly refuses the requests that are not meant to change anything
+func postOnly(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			res.Header().Set("Allow", "POST")
+			writeJSONError(res, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handler(res, req)
+	})
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SAP/node-refiner/pkg/common"
+	"github.com/SAP/node-refiner/pkg/tracing"
+	"github.com/SAP/node-refiner/pkg/types"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// Analysis is the outcome of one run of the calculation pipeline over a snapshot of the cluster
+type Analysis struct {
+	Nodes   map[string]types.NodeManifest
+	Cluster types.ClusterManifest
+
+	// Candidate is the least utilized node, nil when no node could be picked
+	Candidate *types.NodeManifest
+	// CandidateErr explains why no candidate could be picked
+	CandidateErr error
+}
+
+// Analyze runs the calculation pipeline: it assigns the pods to their nodes, calculates the
+// utilization of every node and of the cluster, picks the node to drain and calculates the
+// excess nodes. The NodeManifests in nodesMap are updated in place
+func Analyze(nodesMap map[string]types.NodeManifest, podsMap map[string]types.PodManifest) Analysis {
+	return AnalyzeContext(context.Background(), nodesMap, podsMap, nil)
+}
+
+// AnalyzeContext is Analyze traced as metrics and scoring spans, children of the span in ctx.
+// The pods selected by the exclusions are left out of the utilization or of the drainable nodes
+func AnalyzeContext(ctx context.Context, nodesMap map[string]types.NodeManifest, podsMap map[string]types.PodManifest,
+	exclusions *types.Exclusions) Analysis {
+	_, metricsSpan := tracing.Start(ctx, "metrics")
+	clearPodsList(nodesMap)
+	addPodsToNodes(nodesMap, podsMap)
+	calculateTotalPodsMetrics(nodesMap, exclusions)
+	findDrainBlockers(nodesMap, exclusions)
+	calculateClusterUtilization(nodesMap)
+
+	analysis := Analysis{
+		Nodes:   nodesMap,
+		Cluster: types.NewClusterManifest(nodesMap),
+	}
+	analysis.Cluster.NumberOfPendingPods = countUnschedulablePods(podsMap)
+	metricsSpan.End()
+
+	_, scoringSpan := tracing.Start(ctx, "scoring")
+	defer scoringSpan.End()
+	candidate, err := getNodeToDrain(nodesMap)
+	if err != nil {
+		analysis.CandidateErr = err
+		scoringSpan.SetAttributes(attribute.String("error", err.Error()))
+		return analysis
+	}
+	analysis.Candidate = candidate
+	analysis.Cluster.CalculateExcessNode(candidate)
+	scoringSpan.SetAttributes(attribute.String("candidate", candidate.Node.Name), attribute.Float64("excessNodes", analysis.Cluster.ExcessNodes))
+
+	return analysis
+}
+
+// EstimateCost prices the nodes, the cluster and its excess nodes, nothing is priced when pt is nil
+func (a *Analysis) EstimateCost(pt *types.PriceTable) {
+	a.Cluster.CalculateCost(a.Nodes, a.Candidate, pt)
+	if a.Candidate != nil {
+		candidate := a.Nodes[a.Candidate.Node.Name]
+		a.Candidate = &candidate
+	}
+}
+
+// countUnschedulablePods counts the pending pods that don't fit on any node
+func countUnschedulablePods(podsMap map[string]types.PodManifest) int {
+	count := 0
+	for _, pm := range podsMap {
+		if types.IsUnschedulablePod(pm.Pod) {
+			count++
+		}
+	}
+	return count
+}
+
+func calculateTotalPodsMetrics(nodesMap map[string]types.NodeManifest, exclusions *types.Exclusions) {
+	for key := range nodesMap {
+		totalMetrics := types.PodMetrics{}
+		node := nodesMap[key]
+		for _, pod := range node.Pods {
+			if exclusions.ExcludesFromUtilization(pod.Pod) {
+				continue
+			}
+			totalMetrics.AddPodMetrics(pod.Metrics)
+		}
+		node.TotalPodsRequests = totalMetrics
+		nodesMap[key] = node
+	}
+}
+
+// findDrainBlockers marks the nodes running pods that keep them from being drained
+func findDrainBlockers(nodesMap map[string]types.NodeManifest, exclusions *types.Exclusions) {
+	for key := range nodesMap {
+		node := nodesMap[key]
+		node.DrainBlockedBy = ""
+		for _, pod := range node.Pods {
+			if blocker := exclusions.DrainBlocker(pod.Pod); blocker != "" {
+				node.DrainBlockedBy = blocker
+				break
+			}
+		}
+		nodesMap[key] = node
+	}
+}
+
+func calculateClusterUtilization(nodesMap map[string]types.NodeManifest) {
+	for key := range nodesMap {
+		node := nodesMap[key]
+		node.Utilization = types.CalculateUtilizationPercentage(&node.TotalPodsRequests, node.Metrics)
+		nodesMap[key] = node
+	}
+}
+
+// drainable reports whether the node may be picked to be drained
+func drainable(nm *types.NodeManifest) bool {
+	return !common.CheckForTaints(nm.Node) && nm.DrainBlockedBy == ""
+}
+
+// getRandomNode picks one of the drainable nodes randomly
+func getRandomNode(nodesMap map[string]types.NodeManifest) (*types.NodeManifest, error) {
+	var res types.NodeManifest
+	found := false
+
+	if len(nodesMap) == 0 {
+		return &res, errors.New("couldn't find any node manifests in this map")
+	}
+
+	for _, nm := range nodesMap {
+		if drainable(&nm) {
+			res = nm
+			found = true
+		}
+		if found {
+			break
+		}
+	}
+	if !found {
+		return &res, errors.New("all nodes are tainted or run pods keeping them from being drained, unable to find any node to drain")
+	}
+
+	return &res, nil
+}
+
+// getNodeToDrain get the least utilized node, potentially to drain it
+func getNodeToDrain(nodesMap map[string]types.NodeManifest) (*types.NodeManifest, error) {
+	nmMin, err := getRandomNode(nodesMap)
+	if err != nil {
+		zap.S().Warnw("unable to proceed with picking a node", "error", err)
+		return nmMin, err
+	}
+
+	for i := range nodesMap {
+		nm := nodesMap[i]
+		if drainable(&nm) {
+			if nm.Utilization.Score < nmMin.Utilization.Score {
+				nmMin = &nm
+			}
+		}
+	}
+	return nmMin, nil
+}
+
+// addPodsToNodes assigns Pods to the corresponding node in the nodesMap
+func addPodsToNodes(nodesMap map[string]types.NodeManifest, podsMap map[string]types.PodManifest) {
+	for i := range podsMap {
+		pm := podsMap[i]
+		addIfNodeExists(nodesMap, &pm)
+	}
+}
+
+// clearPodsList clears all the Pods in the NodeManifest object
+func clearPodsList(nodesMap map[string]types.NodeManifest) {
+	for i, val := range nodesMap {
+		val.Pods = make([]*types.PodManifest, 0)
+		nodesMap[i] = val
+	}
+}
+
+// addIfNodeExists add the pod if and only if It was already added in the nodesMap (avoid data race)
+func addIfNodeExists(nodesMap map[string]types.NodeManifest, podManifest *types.PodManifest) {
+	if val, ok := nodesMap[podManifest.Pod.Spec.NodeName]; ok {
+		val.Pods = append(val.Pods, podManifest)
+		nodesMap[podManifest.Pod.Spec.NodeName] = val
+	}
+}
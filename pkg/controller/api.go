@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/common"
+	"github.com/SAP/node-refiner/pkg/drainer"
+	"github.com/SAP/node-refiner/pkg/types"
+
+	"go.uber.org/zap"
+)
+
+// DefaultHistorySize is the number of drain decisions served on /api/v1/history
+const DefaultHistorySize = 100
+
+// ClusterStatus is the cluster of the latest analysis, served on /api/v1/cluster
+type ClusterStatus struct {
+	Time      time.Time `json:"time"`
+	Candidate string    `json:"candidate,omitempty"`
+	types.ClusterReport
+}
+
+// NodeStatus is a node of the latest analysis and whether it may be drained, served on /api/v1/nodes
+type NodeStatus struct {
+	types.NodeReport
+	Ready         bool     `json:"ready"`
+	Unschedulable bool     `json:"unschedulable"`
+	PodNames      []string `json:"podNames"`
+	Drainable     bool     `json:"drainable"`
+	Reason        string   `json:"reason"`
+}
+
+// Decision is the outcome of one run of the calculation loop, served on /api/v1/history
+type Decision struct {
+	Time        time.Time `json:"time"`
+	Candidate   string    `json:"candidate,omitempty"`
+	Drain       bool      `json:"drain"`
+	Reason      string    `json:"reason"`
+	ExcessNodes float64   `json:"excessNodes"`
+}
+
+// apiState keeps the latest analysis and the latest drain decisions for the API
+type apiState struct {
+	mu      sync.RWMutex
+	cluster *ClusterStatus
+	nodes   []NodeStatus
+	// history is a ring buffer, next is where the following decision is written
+	history []Decision
+	next    int
+	full    bool
+}
+
+func newAPIState(historySize int) *apiState {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &apiState{history: make([]Decision, historySize)}
+}
+
+// update replaces the latest analysis and appends the decision to the history
+func (a *apiState) update(now time.Time, analysis *Analysis, decision *drainer.DrainDecision) {
+	cluster := ClusterStatus{Time: now, ClusterReport: types.NewClusterReport(&analysis.Cluster)}
+	entry := Decision{Time: now, ExcessNodes: analysis.Cluster.ExcessNodes}
+	switch {
+	case analysis.Candidate != nil && analysis.CandidateErr == nil:
+		cluster.Candidate = analysis.Candidate.Node.Name
+		entry.Candidate = cluster.Candidate
+		if decision != nil {
+			entry.Drain, entry.Reason = decision.Allowed, decision.Reason
+		}
+	case analysis.CandidateErr != nil:
+		entry.Reason = analysis.CandidateErr.Error()
+	}
+	nodes := newNodeStatuses(analysis, &entry)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cluster = &cluster
+	a.nodes = nodes
+	a.history[a.next] = entry
+	a.next = (a.next + 1) % len(a.history)
+	a.full = a.full || a.next == 0
+}
+
+// newNodeStatuses describes the nodes of the analysis sorted by ascending score, only the
+// candidate of the decision may be drained
+func newNodeStatuses(analysis *Analysis, decision *Decision) []NodeStatus {
+	var candidate *types.NodeManifest
+	if decision.Candidate != "" {
+		candidate = analysis.Candidate
+	}
+
+	reports := types.NewNodeReports(analysis.Nodes, decision.Candidate)
+	statuses := make([]NodeStatus, 0, len(reports))
+	for _, report := range reports {
+		nm := analysis.Nodes[report.Name]
+		status := NodeStatus{
+			NodeReport:    report,
+			Ready:         types.IsNodeReady(nm.Node),
+			Unschedulable: nm.Node.Spec.Unschedulable,
+			PodNames:      make([]string, 0, len(nm.Pods)),
+		}
+		for _, pm := range nm.Pods {
+			status.PodNames = append(status.PodNames, pm.Pod.Namespace+"/"+pm.Pod.Name)
+		}
+
+		switch {
+		case report.Candidate:
+			status.Drainable, status.Reason = decision.Drain, decision.Reason
+		case common.CheckForTaints(nm.Node):
+			status.Reason = "node is tainted or cordoned"
+		case !status.Ready:
+			status.Reason = "node is not ready"
+		case nm.DrainBlockedBy != "":
+			status.Reason = "node runs " + nm.DrainBlockedBy
+		case candidate == nil:
+			status.Reason = decision.Reason
+		default:
+			status.Reason = fmt.Sprintf("node is not the least utilized, its score is %.2f, the score of the candidate %s is %.2f",
+				report.Score, candidate.Node.Name, candidate.Utilization.Score)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// decisions returns at most limit of the latest decisions, oldest first, all of them if limit is not positive
+func (a *apiState) decisions(limit int) []Decision {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	decisions := append([]Decision{}, a.history[:a.next]...)
+	if a.full {
+		decisions = append(append([]Decision{}, a.history[a.next:]...), decisions...)
+	}
+	if limit > 0 && limit < len(decisions) {
+		decisions = decisions[len(decisions)-limit:]
+	}
+	return decisions
+}
+
+// apiHandler serves the read-only API describing the latest analysis and the drainer under /api/v1/
+func (c *WorkloadsController) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/cluster", getOnly(func(res http.ResponseWriter, req *http.Request) {
+		c.api.mu.RLock()
+		cluster := c.api.cluster
+		c.api.mu.RUnlock()
+		if cluster == nil {
+			writeJSONError(res, http.StatusServiceUnavailable, "the cluster was not analysed yet")
+			return
+		}
+		writeJSON(res, http.StatusOK, cluster)
+	}))
+	mux.Handle("/api/v1/nodes", getOnly(func(res http.ResponseWriter, req *http.Request) {
+		c.api.mu.RLock()
+		nodes := c.api.nodes
+		c.api.mu.RUnlock()
+		if nodes == nil {
+			writeJSONError(res, http.StatusServiceUnavailable, "the cluster was not analysed yet")
+			return
+		}
+		writeJSON(res, http.StatusOK, nodes)
+	}))
+	mux.Handle("/api/v1/drainer", getOnly(func(res http.ResponseWriter, req *http.Request) {
+		writeJSON(res, http.StatusOK, c.d.Status(c.clock.Now()))
+	}))
+	mux.Handle("/api/v1/history", getOnly(func(res http.ResponseWriter, req *http.Request) {
+		limit := 0
+		if value := req.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				writeJSONError(res, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", value))
+				return
+			}
+		}
+		writeJSON(res, http.StatusOK, c.api.decisions(limit))
+	}))
+	return mux
+}
+
+// getOnly refuses the requests that would modify anything
+func getOnly(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			res.Header().Set("Allow", "GET, HEAD")
+			writeJSONError(res, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		handler(res, req)
+	})
+}
+
+func writeJSON(res http.ResponseWriter, code int, v interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(code)
+	if err := json.NewEncoder(res).Encode(v); err != nil {
+		zap.S().Warnw("Unable to write the API response", "error", err)
+	}
+}
+
+func writeJSONError(res http.ResponseWriter, code int, message string) {
+	writeJSON(res, code, map[string]string{"error": message})
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/drainer"
+	"github.com/SAP/node-refiner/pkg/types"
+
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func getAPI(t *testing.T, h http.Handler, path string, v interface{}) int {
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+	if res.Code == http.StatusOK {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			t.Fatalf("Unable to decode %s: %v", path, err)
+		}
+	}
+	return res.Code
+}
+
+// TestAPI tests that the API serves the latest analysis, the drainer state and the latest decisions
+func TestAPI(t *testing.T) {
+	fc := clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+	d := drainer.NewAPICordonDrainer(nil, nil)
+	d.SetClock(fc)
+	c := &WorkloadsController{d: d, api: newAPIState(2), clock: fc}
+	h := c.apiHandler()
+
+	var cluster ClusterStatus
+	if code := getAPI(t, h, "/api/v1/cluster", &cluster); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d before the first analysis, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	nodesMap := map[string]types.NodeManifest{}
+	for _, n := range []*v1.Node{
+		testNode("busy", "4", "8Gi", false),
+		testNode("idle", "4", "8Gi", false),
+		testNode("tainted", "4", "8Gi", true),
+	} {
+		n.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+		nodesMap[n.Name] = types.NewNodeManifest(n)
+	}
+	podsMap := map[string]types.PodManifest{}
+	for _, p := range []*v1.Pod{testPod("app", "busy", "3", "1Gi"), testPod("job", "idle", "500m", "1Gi")} {
+		podsMap[p.Name] = types.NewPodManifest(p)
+	}
+	analysis := Analyze(nodesMap, podsMap)
+	for i, reason := range []string{"first", "second", "third"} {
+		c.api.update(fc.Now().Add(time.Duration(i)*time.Minute), &analysis, &drainer.DrainDecision{Reason: reason})
+	}
+
+	if code := getAPI(t, h, "/api/v1/cluster", &cluster); code != http.StatusOK || cluster.Candidate != "idle" || cluster.Nodes != 3 {
+		t.Errorf("Unexpected cluster %d %+v", code, cluster)
+	}
+
+	var nodes []NodeStatus
+	if code := getAPI(t, h, "/api/v1/nodes", &nodes); code != http.StatusOK || len(nodes) != 3 {
+		t.Fatalf("Expected 3 nodes, got %d %+v", code, nodes)
+	}
+	for _, n := range nodes {
+		switch n.Name {
+		case "idle":
+			if !n.Candidate || n.Drainable || n.Reason != "third" || len(n.PodNames) != 1 || n.PodNames[0] != "default/job" {
+				t.Errorf("Expected the candidate with the reason of the latest decision, got %+v", n)
+			}
+		case "tainted":
+			if n.Drainable || !n.Unschedulable || n.Reason != "node is tainted or cordoned" {
+				t.Errorf("Expected the tainted node not to be drainable, got %+v", n)
+			}
+		case "busy":
+			if n.Drainable || n.Candidate || n.Pods != 1 {
+				t.Errorf("Expected the busy node not to be drainable, got %+v", n)
+			}
+		}
+	}
+
+	var history []Decision
+	if code := getAPI(t, h, "/api/v1/history", &history); code != http.StatusOK || len(history) != 2 ||
+		history[0].Reason != "second" || history[1].Reason != "third" || history[1].Candidate != "idle" {
+		t.Errorf("Expected the 2 latest decisions oldest first, got %d %+v", code, history)
+	}
+	if code := getAPI(t, h, "/api/v1/history?limit=1", &history); code != http.StatusOK || len(history) != 1 || history[0].Reason != "third" {
+		t.Errorf("Expected the latest decision, got %d %+v", code, history)
+	}
+	if code := getAPI(t, h, "/api/v1/history?limit=a", &history); code != http.StatusBadRequest {
+		t.Errorf("Expected %d for an invalid limit, got %d", http.StatusBadRequest, code)
+	}
+
+	d.SetLastNodeAddition(fc.Now().Add(-time.Minute))
+	var status drainer.Status
+	if code := getAPI(t, h, "/api/v1/drainer", &status); code != http.StatusOK || status.Phase != drainer.PhaseIdle ||
+		!status.Settings.Enabled || len(status.Cooldowns) != 1 || status.Cooldowns[0].Name != "node addition" {
+		t.Errorf("Unexpected drainer status %d %+v", code, status)
+	}
+
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/v1/drainer", nil))
+	if res.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected %d for a POST, got %d", http.StatusMethodNotAllowed, res.Code)
+	}
+}
+
+func postAdmin(h http.Handler, path string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodPost, path, nil))
+	return res
+}
+
+// TestAdminAPI tests pausing the drainer and requesting drains of specific nodes
+func TestAdminAPI(t *testing.T) {
+	var nodes []runtime.Object
+	c := &WorkloadsController{
+		podsMap:  make(map[string]types.PodManifest),
+		nodesMap: make(map[string]types.NodeManifest),
+		clock:    clock.RealClock{},
+	}
+	for _, n := range []*v1.Node{
+		testNode("a", "4", "8Gi", false),
+		testNode("b", "4", "8Gi", false),
+		testNode("c", "4", "8Gi", false),
+		testNode("tainted", "4", "8Gi", true),
+	} {
+		n.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+		c.nodesMap[n.Name] = types.NewNodeManifest(n)
+		nodes = append(nodes, n)
+	}
+	client := fake.NewSimpleClientset(nodes...)
+	c.d = drainer.NewAPICordonDrainer(client, nil)
+	h := c.adminHandler()
+
+	if res := postAdmin(h, "/api/v1/admin/pause"); res.Code != http.StatusOK || !c.d.Paused() {
+		t.Fatalf("Expected the drainer to be paused, got %d %s", res.Code, res.Body)
+	}
+	if res := postAdmin(h, "/api/v1/admin/drain?node=b"); res.Code != http.StatusConflict {
+		t.Errorf("Expected %d while paused, got %d %s", http.StatusConflict, res.Code, res.Body)
+	}
+	if res := postAdmin(h, "/api/v1/admin/resume"); res.Code != http.StatusOK || c.d.Paused() {
+		t.Fatalf("Expected the drainer to be resumed, got %d %s", res.Code, res.Body)
+	}
+
+	for path, code := range map[string]int{
+		"/api/v1/admin/drain":              http.StatusBadRequest,
+		"/api/v1/admin/drain?node=missing": http.StatusNotFound,
+		"/api/v1/admin/drain?node=tainted": http.StatusConflict,
+		"/api/v1/admin/abort":              http.StatusConflict,
+	} {
+		if res := postAdmin(h, path); res.Code != code {
+			t.Errorf("Expected %d for %s, got %d %s", code, path, res.Code, res.Body)
+		}
+	}
+
+	var decision drainer.DrainDecision
+	res := postAdmin(h, "/api/v1/admin/drain?node=b")
+	if err := json.NewDecoder(res.Body).Decode(&decision); err != nil || res.Code != http.StatusAccepted || !decision.Allowed {
+		t.Fatalf("Expected the drain of b to be accepted, got %d %+v %v", res.Code, decision, err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if n, err := client.CoreV1().Nodes().Get(context.TODO(), "b", meta_v1.GetOptions{}); err == nil && n.Spec.Unschedulable {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("The requested node wasn't cordoned")
+		}
+	}
+
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/v1/admin/pause", nil))
+	if res.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected %d for a GET, got %d", http.StatusMethodNotAllowed, res.Code)
+	}
+}
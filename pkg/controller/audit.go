@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/audit"
+	"github.com/SAP/node-refiner/pkg/drainer"
+)
+
+// audit records a decision or an action when the audit log is enabled
+func (c *WorkloadsController) audit(record audit.Record) {
+	if c.auditor != nil {
+		c.auditor.Audit(record)
+	}
+}
+
+// numbers are the counts and remaining times of the reasons, ignored when comparing decisions
+var numbers = regexp.MustCompile(`[0-9]+(\.[0-9]+)?`)
+
+// auditDecision records whether the drainer would scale the node down, the decision is nil
+// when no candidate could be picked. Refused decisions are only recorded when they differ from
+// the previous one, so that the loop doesn't push the scale downs out of the audit ConfigMap
+func (c *WorkloadsController) auditDecision(now time.Time, analysis *Analysis, decision *drainer.DrainDecision) {
+	record := audit.Record{
+		Time:         now,
+		Kind:         audit.KindDecision,
+		SettingsHash: c.d.SettingsHash(),
+		Result:       audit.ResultRefused,
+	}
+	if analysis.Candidate != nil {
+		record.Node = analysis.Candidate.Node.Name
+	}
+	switch {
+	case decision != nil:
+		record.Reason = decision.Reason
+		if decision.Allowed {
+			record.Result = audit.ResultAllowed
+		}
+	case analysis.CandidateErr != nil:
+		record.Reason = analysis.CandidateErr.Error()
+	}
+
+	key := fmt.Sprintf("%s/%s/%s/%s", record.Node, record.Result, record.SettingsHash, numbers.ReplaceAllString(record.Reason, "#"))
+	if record.Result == audit.ResultRefused && key == c.lastDecision {
+		return
+	}
+	c.lastDecision = key
+	c.audit(record)
+}
+
+// auditUncordon records a node uncordoned for the pending pods
+func (c *WorkloadsController) auditUncordon(node string, pendingPods int, err error) {
+	record := audit.Record{
+		Time:   c.clock.Now(),
+		Kind:   audit.KindUncordon,
+		Node:   node,
+		Reason: fmt.Sprintf("%d pending pods fit on no node", pendingPods),
+		Result: audit.ResultSucceeded,
+	}
+	if err != nil {
+		record.Result = audit.ResultFailed
+		record.Error = err.Error()
+	}
+	c.audit(record)
+}
+
+// auditAdmin records a request to the admin API and its result, failed when err is set
+func (c *WorkloadsController) auditAdmin(request, user, node, result string, err error) {
+	record := audit.Record{
+		Time:         c.clock.Now(),
+		Kind:         audit.KindAdmin,
+		Node:         node,
+		Reason:       request,
+		SettingsHash: c.d.SettingsHash(),
+		User:         user,
+		Result:       result,
+	}
+	if err != nil {
+		record.Result = audit.ResultFailed
+		record.Error = err.Error()
+	}
+	c.audit(record)
+}
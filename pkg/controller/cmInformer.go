@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/cache"
@@ -22,12 +23,9 @@ func (c *WorkloadsController) AddConfigMapEventHandler() {
 func (c *WorkloadsController) addConfigMap(obj interface{}) {
 	cm := obj.(*corev1.ConfigMap)
 
-	if cm.Name == "node-refiner-cm" {
+	if c.isSettingsConfigMap(cm) {
 		zap.S().Info("ConfigMap add event, initiating an update to the drainer settings")
-		err := c.d.UpdateSettings(cm)
-		if err != nil {
-			zap.S().Warnw("Couldn't update the drainer settings using ConfigMap", "error", err)
-		}
+		c.updateSettings(cm)
 
 	}
 }
@@ -38,12 +36,9 @@ func (c *WorkloadsController) addConfigMap(obj interface{}) {
 func (c *WorkloadsController) updateConfigMap(old, new interface{}) {
 	// Cast the obj as ConfigMap
 	cmNew := new.(*corev1.ConfigMap)
-	if cmNew.Name == "node-refiner-cm" {
+	if c.isSettingsConfigMap(cmNew) {
 		zap.S().Info("ConfigMap update event, initiating an update to the drainer settings")
-		err := c.d.UpdateSettings(cmNew)
-		if err != nil {
-			zap.S().Warnw("Couldn't update the drainer settings using ConfigMap", "error", err)
-		}
+		c.updateSettings(cmNew)
 	}
 }
 
@@ -53,3 +48,32 @@ func (c *WorkloadsController) deleteConfigMap(obj interface{}) {
 	cm := obj.(*corev1.ConfigMap)
 	zap.S().Infow("Deleted a config map", "name", cm.Name)
 }
+
+// updateSettings applies the settings ConfigMap to the drainer, the controller is not ready
+// while the settings are invalid
+func (c *WorkloadsController) updateSettings(cm *corev1.ConfigMap) {
+	err := c.d.UpdateSettings(cm)
+	if err != nil {
+		zap.S().Warnw("Couldn't update the drainer settings using ConfigMap", "error", err)
+		err = errors.Wrapf(err, "invalid settings in ConfigMap %s/%s", cm.Namespace, cm.Name)
+	}
+	c.health.SetComponent(componentConfig, err)
+}
+
+// hasSettingsConfigMap checks whether the informer found the ConfigMap holding the controller settings
+func (c *WorkloadsController) hasSettingsConfigMap() bool {
+	for _, obj := range c.cmInformer.GetStore().List() {
+		if cm, ok := obj.(*corev1.ConfigMap); ok && c.isSettingsConfigMap(cm) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSettingsConfigMap checks whether the ConfigMap is the one holding the controller settings
+func (c *WorkloadsController) isSettingsConfigMap(cm *corev1.ConfigMap) bool {
+	if c.configMapNamespace != "" && cm.Namespace != c.configMapNamespace {
+		return false
+	}
+	return cm.Name == c.configMapName
+}
@@ -2,24 +2,133 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"sync"
 	"time"
 
+	"github.com/SAP/node-refiner/pkg/audit"
 	"github.com/SAP/node-refiner/pkg/common"
 	"github.com/SAP/node-refiner/pkg/drainer"
+	"github.com/SAP/node-refiner/pkg/notifier"
+	"github.com/SAP/node-refiner/pkg/snapshot"
 	"github.com/SAP/node-refiner/pkg/supervisor"
+	"github.com/SAP/node-refiner/pkg/tracing"
 	"github.com/SAP/node-refiner/pkg/types"
 
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
 )
 
+// Backoff applied when restarting the supervisor servers after a failure
+const (
+	serverInitialBackoff = 1 * time.Second
+	serverMaxBackoff     = 2 * time.Minute
+	serverBackoffReset   = 10 * time.Minute
+	serverBackoffFactor  = 2.0
+	serverBackoffJitter  = 0.1
+)
+
+// Default values for the controller options
+const (
+	DefaultMetricsPrefix      = "node_refiner"
+	DefaultConfigMapName      = "node-refiner-cm"
+	DefaultConfigMapNamespace = ""
+	DefaultLoopInterval       = 1 * time.Minute
+)
+
+// Components of the controller reported on the readiness and status endpoints
+const (
+	componentInformers = "informers"
+	componentConfig    = "config"
+	componentAPI       = "api"
+)
+
+// The API server is probed with a timeout, at most once per interval whatever the number of probes
+const (
+	apiCheckTimeout  = 5 * time.Second
+	apiCheckInterval = 10 * time.Second
+)
+
+// Options configures how the controller connects to the cluster, where it reads
+// its settings from and how it exposes its metrics
+type Options struct {
+	// Kubeconfig path, empty means in-cluster config or the default loading rules
+	Kubeconfig string
+	// Context of the kubeconfig to use, empty means the current context
+	Context string
+
+	MetricsPrefix string
+	// Server serving the metrics and the health endpoints
+	Server supervisor.ServerOptions
+
+	// ConfigMap holding the drainer settings, an empty namespace watches all namespaces
+	ConfigMapName      string
+	ConfigMapNamespace string
+
+	// LoopInterval time between two runs of the calculation loop
+	LoopInterval time.Duration
+
+	// HistorySize is the number of drain decisions served by the API
+	HistorySize int
+
+	// The admin API authenticates its requests with the bearer token in AdminTokenFile or, if
+	// AdminKubernetesAuth is set, with a TokenReview and a SubjectAccessReview. It is disabled otherwise
+	AdminTokenFile      string
+	AdminKubernetesAuth bool
+
+	// NotificationConfig is the file listing the webhooks told about scale downs, disabled when empty
+	NotificationConfig string
+
+	// Audit selects where the decisions and actions are recorded, disabled without a sink
+	Audit audit.Options
+
+	// PriceTable is the file the node prices are read from, costs are not estimated when empty
+	PriceTable string
+
+	// Exclusions is the file selecting the pods left out of the utilization or keeping their
+	// nodes from being drained, by namespace, labels or priority. No pod is excluded when empty
+	Exclusions string
+	// DrainPriorityThreshold overrides the priority threshold of the exclusions when not nil
+	DrainPriorityThreshold *int32
+
+	// Recorder writes every iteration of the calculation loop to disk, disabled when its Dir is empty
+	Recorder snapshot.RecorderOptions
+}
+
+// DefaultOptions returns the options the controller runs with when nothing is configured
+func DefaultOptions() Options {
+	return Options{
+		MetricsPrefix:      DefaultMetricsPrefix,
+		Server:             supervisor.DefaultServerOptions(),
+		ConfigMapName:      DefaultConfigMapName,
+		ConfigMapNamespace: DefaultConfigMapNamespace,
+		LoopInterval:       DefaultLoopInterval,
+		HistorySize:        DefaultHistorySize,
+		Audit:              audit.Options{ConfigMapSize: audit.DefaultConfigMapSize},
+		Recorder: snapshot.RecorderOptions{
+			MaxFileSize:  snapshot.DefaultMaxFileSize,
+			MaxFileAge:   snapshot.DefaultMaxFileAge,
+			MaxAge:       snapshot.DefaultMaxAge,
+			MaxTotalSize: snapshot.DefaultMaxTotalSize,
+		},
+	}
+}
+
+// ErrCacheSync is returned when an informer cache could not be synced before
+// the controller was asked to stop
+var ErrCacheSync = errors.New("informer cache failed to sync")
+
 // WorkloadsController central controller that manages the communication between the different modules
 type WorkloadsController struct {
 	client kubernetes.Interface
@@ -30,62 +139,254 @@ type WorkloadsController struct {
 	// Prometheus Supervision
 	s *supervisor.Supervisor
 
+	// Liveness and readiness of the controller components
+	health *supervisor.Health
+
+	// Snapshot Recorder, nil when disabled
+	recorder *snapshot.Recorder
+
+	// Audit log of the decisions and actions, nil when disabled
+	auditor audit.Auditor
+	// Closed once the audit log wrote the records left and closed its sinks, nil when disabled
+	auditDone chan struct{}
+
 	// Informers
 	nodesInformer cache.SharedIndexInformer
 	podsInformer  cache.SharedIndexInformer
 	cmInformer    cache.SharedIndexInformer
 
-	// Cluster State
+	// Cluster State, written by the informers and guarded by mu
+	mu       sync.RWMutex
 	podsMap  map[string]types.PodManifest
 	nodesMap map[string]types.NodeManifest
+
+	// Latest analysis and drain decisions served by the API
+	api *apiState
+
+	// Prices of the nodes, nil when costs are not estimated
+	prices *types.PriceTable
+	// Pods left out of the utilization or of the drainable nodes, nil when none is excluded
+	exclusions *types.Exclusions
+
+	// Pods are pending since the previous run of the calculation loop, only used by the loop
+	underPressure bool
+	// Identifies the latest refused decision audited, only used by the loop
+	lastDecision string
+
+	// Time source of the calculation loop and the drainer
+	clock clock.Clock
+
+	// Settings
+	configMapName      string
+	configMapNamespace string
+	loopInterval       time.Duration
+
+	stopCh <-chan struct{}
 }
 
 // NewController creates a new controller and returns a pointer to the created object
-func NewController() (*WorkloadsController, error) {
+func NewController(opts Options) (*WorkloadsController, error) {
 
-	var kubeClient kubernetes.Interface
+	kubeClient, err := common.GetClientFor(opts.Kubeconfig, opts.Context)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to instantiate a client")
+	}
+
+	realClock := clock.RealClock{}
+	health := supervisor.NewHealth(realClock, opts.LoopInterval)
+	s := supervisor.InitSupervisor(opts.MetricsPrefix, health, opts.Server)
+	d := drainer.NewAPICordonDrainer(kubeClient, s)
+	if err := d.DiscoverEvictionAPI(); err != nil {
+		return nil, err
+	}
 
-	if _, err := rest.InClusterConfig(); err != nil {
-		kubeClient, err = common.GetClientOutOfCluster()
+	adminAuth, err := newAdminAuthenticator(opts, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var notify *notifier.WebhookNotifier
+	if opts.NotificationConfig != "" {
+		config, err := notifier.LoadConfig(opts.NotificationConfig)
 		if err != nil {
-			zap.S().Warn("Unable to instantiate a client")
+			return nil, err
 		}
-	} else {
-		kubeClient, err = common.GetClient()
-		if err != nil {
-			zap.S().Warn("Unable to instantiate a client")
+		if notify, err = notifier.New(config, realClock); err != nil {
+			return nil, errors.Wrap(err, "invalid notifier config")
 		}
 	}
 
-	s := supervisor.InitSupervisor("node_refiner")
-	d := drainer.NewAPICordonDrainer(kubeClient, s)
+	var prices *types.PriceTable
+	if opts.PriceTable != "" {
+		if prices, err = types.LoadPriceTable(opts.PriceTable); err != nil {
+			return nil, err
+		}
+		d.SetPriceTable(prices)
+		s.RegisterSavings(func() float64 { return d.EstimatedSavings(realClock.Now()) })
+	}
 
-	go s.StartSupervising()
+	var exclusions *types.Exclusions
+	if opts.Exclusions != "" {
+		if exclusions, err = types.LoadExclusions(opts.Exclusions); err != nil {
+			return nil, err
+		}
+	}
+	if exclusions = exclusions.WithPriorityThreshold(opts.DrainPriorityThreshold); exclusions != nil {
+		d.SetExclusions(exclusions)
+	}
+
+	var auditLogger *audit.Logger
+	if opts.Audit.Enabled() {
+		if auditLogger, err = audit.New(opts.Audit, kubeClient); err != nil {
+			return nil, err
+		}
+		d.SetAuditor(auditLogger)
+	}
+
+	var recorder *snapshot.Recorder
+	if opts.Recorder.Dir != "" {
+		recorder, err = snapshot.NewRecorder(opts.Recorder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d.SetClock(realClock)
+	stopCh := common.CreateSignalHandler()
+	go runWithBackoff("server", func() error { return s.Serve(stopCh) }, realClock, stopCh)
 
 	controller := WorkloadsController{
 		client:   kubeClient,
 		d:        d,
 		s:        s,
+		health:   health,
 		podsMap:  make(map[string]types.PodManifest),
 		nodesMap: make(map[string]types.NodeManifest),
+		api:      newAPIState(opts.HistorySize),
+		prices:   prices,
+
+		exclusions: exclusions,
+
+		recorder: recorder,
+		clock:    realClock,
+
+		configMapName:      opts.ConfigMapName,
+		configMapNamespace: opts.ConfigMapNamespace,
+		loopInterval:       opts.LoopInterval,
+
+		stopCh: stopCh,
+	}
+	controller.watchHealth()
+	if auditLogger != nil {
+		controller.auditDone = make(chan struct{})
+		go func() {
+			defer close(controller.auditDone)
+			auditLogger.Run(stopCh)
+		}()
+		controller.auditor = auditLogger
+	}
+	if notify != nil {
+		go notify.Run(stopCh)
+		d.SetNotifier(notify)
+		d.SetClusterSource(func() types.ClusterManifest {
+			return controller.analyze(context.Background()).Cluster
+		})
+	}
+	s.Handle("/api/v1/", controller.apiHandler())
+	if adminAuth != nil {
+		s.Handle("/api/v1/admin/", supervisor.RequireAuth(adminAuth, controller.adminHandler()))
+	} else {
+		zap.S().Info("Admin API disabled, neither an admin token nor the Kubernetes authentication is configured")
 	}
 
 	return &controller, nil
 }
 
-// CreateRunInformers create and run the informers in a parallel thread
-func (c *WorkloadsController) CreateRunInformers() {
+// watchHealth registers the components the controller is ready once they reported healthy:
+// the informers are synced, the settings are loaded and the API server is reachable
+func (c *WorkloadsController) watchHealth() {
+	c.health.SetComponent(componentInformers, supervisor.ErrNotReported)
+	c.health.SetComponent(componentConfig, supervisor.ErrNotReported)
+	api := &apiCheck{clock: c.clock, probe: func(ctx context.Context) error {
+		rc := c.client.Discovery().RESTClient()
+		if rc == nil {
+			// Fake clients have no REST client to send the request with
+			_, err := c.client.Discovery().ServerVersion()
+			return err
+		}
+		return rc.Get().AbsPath("/version").Do(ctx).Error()
+	}}
+	c.health.AddReadinessCheck(componentAPI, api.check)
+	c.health.SetDrainerPhase(c.d.Phase)
+}
+
+// apiCheck reports whether the API server is reachable. The result of a probe is reused for
+// apiCheckInterval and concurrent checks wait for the probe in flight, so that the readiness and
+// status endpoints don't load a slow API server
+type apiCheck struct {
+	mu      sync.Mutex
+	clock   clock.PassiveClock
+	probe   func(ctx context.Context) error
+	checked time.Time
+	err     error
+}
+
+func (a *apiCheck) check() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.checked.IsZero() && a.clock.Since(a.checked) < apiCheckInterval {
+		return a.err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiCheckTimeout)
+	defer cancel()
+	a.err = a.probe(ctx)
+	a.checked = a.clock.Now()
+	return a.err
+}
+
+// runWithBackoff keeps a long-running component alive by restarting it with an
+// exponential backoff whenever it returns, until stopCh is closed. The backoff starts
+// over once the component ran for longer than serverBackoffReset
+func runWithBackoff(name string, run func() error, clock clock.Clock, stopCh <-chan struct{}) {
+	initial := wait.Backoff{Duration: serverInitialBackoff, Factor: serverBackoffFactor, Jitter: serverBackoffJitter,
+		Steps: math.MaxInt32, Cap: serverMaxBackoff}
+	backoff := initial
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+		started := clock.Now()
+		err := run()
+		if clock.Since(started) >= serverBackoffReset {
+			backoff = initial
+		}
+		delay := backoff.Step()
+		zap.S().Errorw("Component stopped, restarting it with backoff", "component", name, "retryIn", delay, "error", err)
+		select {
+		case <-stopCh:
+			return
+		case <-clock.After(delay):
+		}
+	}
+}
+
+// CreateRunInformers create and run the informers in a parallel thread,
+// blocks until the controller is stopped
+func (c *WorkloadsController) CreateRunInformers() error {
 
 	factory := informers.NewSharedInformerFactory(c.client, 10*time.Minute)
+	cmFactory := informers.NewSharedInformerFactoryWithOptions(c.client, 10*time.Minute, informers.WithNamespace(c.configMapNamespace))
 
 	podsInformer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
 			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-				options.FieldSelector = "status.phase!=Succeeded,status.phase!=Failed,status.phase!=Unknown"
+				options.FieldSelector = types.ActivePodsFieldSelector
 				return c.client.CoreV1().Pods(corev1.NamespaceAll).List(context.TODO(), options)
 			},
 			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-				options.FieldSelector = "status.phase!=Succeeded,status.phase!=Failed,status.phase!=Unknown"
+				options.FieldSelector = types.ActivePodsFieldSelector
 				return c.client.CoreV1().Pods(corev1.NamespaceAll).Watch(context.TODO(), options)
 			},
 		},
@@ -96,13 +397,14 @@ func (c *WorkloadsController) CreateRunInformers() {
 
 	c.podsInformer = podsInformer
 	// c.podsInformer = factory.Core().V1().Pods().Informer()
-	c.cmInformer = factory.Core().V1().ConfigMaps().Informer()
+	c.cmInformer = cmFactory.Core().V1().ConfigMaps().Informer()
 	c.nodesInformer = factory.Core().V1().Nodes().Informer()
 
 	// Starting the factory will start all informers created
 	// by this factory
-	stopCh := common.CreateSignalHandler()
+	stopCh := c.stopCh
 	factory.Start(stopCh)
+	cmFactory.Start(stopCh)
 	go c.podsInformer.Run(stopCh)
 	zap.S().Info("Informers running")
 
@@ -111,15 +413,20 @@ func (c *WorkloadsController) CreateRunInformers() {
 	// WaitForCacheSync which will also take care of signal
 	// handling, i.e. it returns when stopCh is closed
 	if ok := cache.WaitForCacheSync(stopCh, c.podsInformer.HasSynced); !ok {
-		panic("Error while waiting for pods informer to sync")
+		return c.reportInformers(fmt.Errorf("%w: pods", ErrCacheSync))
 	}
 
 	if ok := cache.WaitForCacheSync(stopCh, c.cmInformer.HasSynced); !ok {
-		panic("Error while waiting for config maps informer to sync")
+		return c.reportInformers(fmt.Errorf("%w: config maps", ErrCacheSync))
 	}
 
 	if ok := cache.WaitForCacheSync(stopCh, c.nodesInformer.HasSynced); !ok {
-		panic("Error while waiting for nodes informer to sync")
+		return c.reportInformers(fmt.Errorf("%w: nodes", ErrCacheSync))
+	}
+	c.reportInformers(nil)
+	if !c.hasSettingsConfigMap() {
+		zap.S().Warnw("Settings ConfigMap not found, using the default drainer settings", "name", c.configMapName, "namespace", c.configMapNamespace)
+		c.health.SetComponent(componentConfig, nil)
 	}
 
 	c.AddNodeEventHandler()
@@ -128,57 +435,149 @@ func (c *WorkloadsController) CreateRunInformers() {
 
 	<-stopCh
 	zap.S().Info("Stopping Node Refiner")
-}
-
-func (c *WorkloadsController) calculateTotalPodsMetrics() {
-	for key := range c.nodesMap {
-		totalMetrics := types.PodMetrics{}
-		node := c.nodesMap[key]
-		for _, pod := range node.Pods {
-			totalMetrics.AddPodMetrics(pod.Metrics)
+	if c.recorder != nil {
+		if err := c.recorder.Close(); err != nil {
+			zap.S().Warnw("Unable to close the snapshot recorder", "error", err)
 		}
-		node.TotalPodsRequests = totalMetrics
-		c.nodesMap[key] = node
 	}
+	if c.auditDone != nil {
+		<-c.auditDone
+	}
+	return nil
 }
 
-func (c *WorkloadsController) calculateClusterUtilization() {
-	for key := range c.nodesMap {
-		node := c.nodesMap[key]
-		node.Utilization = types.CalculateUtilizationPercentage(&node.TotalPodsRequests, node.Metrics)
-		c.nodesMap[key] = node
-	}
+// reportInformers records whether the informers synced and hands the error back
+func (c *WorkloadsController) reportInformers(err error) error {
+	c.health.SetComponent(componentInformers, err)
+	return err
 }
 
-// RunCalculationLoop Run the cluster calculation loop every minute
+// RunCalculationLoop Run the cluster calculation loop every loop interval until the controller is stopped
 func (c *WorkloadsController) RunCalculationLoop() {
 	for {
-		supervisor.UpdateHeartbeat()
-		c.clearPodsList()
-		c.addPodsToNodes()
-		c.calculateTotalPodsMetrics()
-		c.calculateClusterUtilization()
-		cluster := types.NewClusterManifest(c.nodesMap)
-		potentialNodeDrain, err := c.getNodeToDrain()
-		if err != nil {
-			zap.S().Warn("Not ready to get nodes to drain")
-		} else {
-			zap.S().Infow("Potential node to drain",
-				"node", potentialNodeDrain.Node.Name, "number of pods", len(potentialNodeDrain.Pods),
-				"CPU Utilization", common.FormatPercentage(potentialNodeDrain.Utilization.PercentageCPU),
-				"RAM Utilization", common.FormatPercentage(potentialNodeDrain.Utilization.PercentageRAM))
-			cluster.CalculateExcessNode(potentialNodeDrain)
-			c.d.AttemptDrain(potentialNodeDrain.Node.Name, &cluster)
+		c.runCalculation()
+
+		select {
+		case <-c.stopCh:
+			return
+		case <-c.clock.After(c.loopInterval):
 		}
+	}
+}
+
+// runCalculation runs one iteration of the calculation loop, traced as a calculation-loop span
+func (c *WorkloadsController) runCalculation() {
+	ctx, span := tracing.Start(context.Background(), "calculation-loop")
+	defer span.End()
+	log := tracing.Logger(ctx)
+
+	now := c.clock.Now()
+	_, snapshotSpan := tracing.Start(ctx, "snapshot")
+	nodesMap, podsMap := c.copyMaps()
+	snapshotSpan.SetAttributes(attribute.Int("nodes", len(nodesMap)), attribute.Int("pods", len(podsMap)))
+	snapshotSpan.End()
+
+	analysis := AnalyzeContext(ctx, nodesMap, podsMap, c.exclusions)
+	analysis.EstimateCost(c.prices)
+	cluster := analysis.Cluster
+	switch {
+	case cluster.NumberOfPendingPods == 0:
+		c.underPressure = false
+	case !c.underPressure:
+		// Only once per pressure episode, pods still pending after that are not a drain's doing
+		c.underPressure = true
+		c.relievePressure(cluster.NumberOfPendingPods)
+	}
+	potentialNodeDrain := analysis.Candidate
+	var decision *drainer.DrainDecision
+	if analysis.CandidateErr != nil {
+		log.Warn("Not ready to get nodes to drain")
+	} else {
+		log.Infow("Potential node to drain",
+			"node", potentialNodeDrain.Node.Name, "number of pods", len(potentialNodeDrain.Pods),
+			"CPU Utilization", common.FormatPercentage(potentialNodeDrain.Utilization.PercentageCPU),
+			"RAM Utilization", common.FormatPercentage(potentialNodeDrain.Utilization.PercentageRAM))
+		_, gatingSpan := tracing.Start(ctx, "gating", attribute.String("node", potentialNodeDrain.Node.Name))
+		d := c.d.AttemptDrain(potentialNodeDrain.Node.Name, &cluster)
+		gatingSpan.SetAttributes(attribute.Bool("allowed", d.Allowed), attribute.String("reason", d.Reason))
+		gatingSpan.End()
+		decision = &d
+	}
+	c.record(now, &analysis, decision)
+	c.auditDecision(now, &analysis, decision)
+	c.api.update(now, &analysis, decision)
 
-		logCluster(&cluster)
+	logCluster(&cluster)
+	if c.s != nil {
 		c.s.ClusterMetrics.PublishClusterMetrics(&cluster)
-		c.s.ClusterMetrics.PublishNodeUnschedulable(c.nodesMap)
-		//types.TabulateNodeMap(c.nodesMap)
-		//types.TabulatePodsMap(c.podsMap)
-		//types.TabulateCluster(&cluster)
+		c.s.ClusterMetrics.PublishNodeUnschedulable(analysis.Nodes)
+	}
+	c.health.Beat(c.clock.Now(), c.clock.Since(now))
+}
+
+// analyze runs the calculation pipeline over a copy of the cluster state
+func (c *WorkloadsController) analyze(ctx context.Context) Analysis {
+	nodesMap, podsMap := c.copyMaps()
+	return AnalyzeContext(ctx, nodesMap, podsMap, c.exclusions)
+}
 
-		time.Sleep(1 * time.Minute)
+// copyMaps returns copies of the cluster state the informers keep updating, Analyze
+// may then modify them without holding the lock
+func (c *WorkloadsController) copyMaps() (map[string]types.NodeManifest, map[string]types.PodManifest) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	nodesMap := make(map[string]types.NodeManifest, len(c.nodesMap))
+	for name, nm := range c.nodesMap {
+		nodesMap[name] = nm
+	}
+	podsMap := make(map[string]types.PodManifest, len(c.podsMap))
+	for name, pm := range c.podsMap {
+		podsMap[name] = pm
+	}
+	return nodesMap, podsMap
+}
+
+// relievePressure aborts the scale down in progress, if any, and gives its node back to pods that fit nowhere else
+func (c *WorkloadsController) relievePressure(pendingPods int) {
+	node, err := c.d.RelievePressure()
+	if node != "" {
+		c.auditUncordon(node, pendingPods, err)
+	}
+	if err != nil {
+		zap.S().Warnw("Unable to uncordon a node for the pending pods", "node", node, "pendingPods", pendingPods, "error", err)
+		return
+	}
+	if node != "" {
+		zap.S().Infow("Uncordoned a node for the pending pods", "node", node, "pendingPods", pendingPods)
+	}
+}
+
+// record writes the iteration to the snapshot recorder if it is enabled
+func (c *WorkloadsController) record(now time.Time, analysis *Analysis, decision *drainer.DrainDecision) {
+	if c.recorder == nil {
+		return
+	}
+
+	record := snapshot.Record{
+		Snapshot: snapshot.Snapshot{Time: now},
+		Cluster:  types.NewClusterReport(&analysis.Cluster),
+	}
+	for _, nm := range analysis.Nodes {
+		record.Nodes = append(record.Nodes, *nm.Node)
+		for _, pm := range nm.Pods {
+			record.Pods = append(record.Pods, *pm.Pod)
+		}
+	}
+	if analysis.Candidate != nil {
+		record.Candidate = analysis.Candidate.Node.Name
+	}
+	record.Utilization = types.NewNodeReports(analysis.Nodes, record.Candidate)
+	if decision != nil {
+		record.Decision = &snapshot.Decision{Drain: decision.Allowed, Reason: decision.Reason}
+	}
+
+	if err := c.recorder.Record(&record); err != nil {
+		zap.S().Warnw("Unable to record the cluster snapshot", "error", err)
 	}
 }
 
@@ -186,9 +585,18 @@ func logCluster(clusterManifest *types.ClusterManifest) {
 	zap.S().Infow("Cluster State",
 		"Number of nodes", clusterManifest.NumberOfNodes,
 		"Number of non tainted nodes", clusterManifest.NumberOfNonTaintedNodes,
+		"Number of not ready nodes", clusterManifest.NumberOfNotReadyNodes,
 		"Number of pods", clusterManifest.NumberOfPods,
+		"Number of pending pods", clusterManifest.NumberOfPendingPods,
 		"Number of excess nodes", clusterManifest.ExcessNodes,
 		"CPU Utilization", common.FormatPercentage(clusterManifest.Utilization.PercentageCPU),
 		"RAM Utilization", common.FormatPercentage(clusterManifest.Utilization.PercentageRAM),
 	)
+	if clusterManifest.Priced {
+		zap.S().Infow("Cluster Cost",
+			"Cost per hour", clusterManifest.CostPerHour,
+			"Excess cost per hour", clusterManifest.ExcessCostPerHour,
+			"Currency", clusterManifest.Currency,
+		)
+	}
 }
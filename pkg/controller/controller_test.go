@@ -1,15 +1,22 @@
 package controller
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/SAP/node-refiner/pkg/audit"
 	"github.com/SAP/node-refiner/pkg/drainer"
+	"github.com/SAP/node-refiner/pkg/supervisor"
 	"github.com/SAP/node-refiner/pkg/types"
+	"github.com/pkg/errors"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
 )
 
 func pod(namespace, image string) *v1.Pod {
@@ -24,7 +31,13 @@ func testController(client *fake.Clientset) *WorkloadsController {
 		s:        nil,
 		podsMap:  make(map[string]types.PodManifest),
 		nodesMap: make(map[string]types.NodeManifest),
+		api:      newAPIState(DefaultHistorySize),
+		health:   supervisor.NewHealth(nil, time.Minute),
+		clock:    clock.RealClock{},
+
+		configMapName: DefaultConfigMapName,
 	}
+	controller.watchHealth()
 
 	go controller.CreateRunInformers()
 
@@ -38,8 +51,370 @@ func TestControllerPodList(t *testing.T) {
 
 	time.Sleep(1 * time.Second)
 
+	controller.mu.RLock()
+	defer controller.mu.RUnlock()
 	if len(controller.podsMap) != 1 {
 		t.Errorf("Expected 1 pod, got %d", len(controller.podsMap))
 	}
 
 }
+
+func testNode(name, cpu, memory string, tainted bool) *v1.Node {
+	n := &v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: name}, Spec: v1.NodeSpec{Unschedulable: tainted}}
+	n.Status.Allocatable = v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)}
+	return n
+}
+
+func testPod(name, nodeName, cpu, memory string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "default"}, Spec: v1.PodSpec{
+		NodeName: nodeName,
+		Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+			v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)}}}},
+	}}
+}
+
+// TestAnalyzePicksLeastUtilizedNode tests that the pipeline picks the least utilized non-tainted node
+func TestAnalyzePicksLeastUtilizedNode(t *testing.T) {
+	nodesMap := map[string]types.NodeManifest{}
+	for _, n := range []*v1.Node{
+		testNode("busy", "4", "8Gi", false),
+		testNode("idle", "4", "8Gi", false),
+		testNode("tainted", "4", "8Gi", true),
+	} {
+		nodesMap[n.Name] = types.NewNodeManifest(n)
+	}
+	podsMap := map[string]types.PodManifest{}
+	for _, p := range []*v1.Pod{
+		testPod("a", "busy", "3", "4Gi"),
+		testPod("b", "idle", "500m", "1Gi"),
+	} {
+		podsMap[p.Name] = types.NewPodManifest(p)
+	}
+
+	analysis := Analyze(nodesMap, podsMap)
+
+	if analysis.Candidate == nil || analysis.Candidate.Node.Name != "idle" {
+		t.Fatalf("Expected idle to be the candidate, got %+v (%v)", analysis.Candidate, analysis.CandidateErr)
+	}
+	if analysis.Cluster.NumberOfNonTaintedNodes != 2 {
+		t.Errorf("Expected 2 non tainted nodes, got %d", analysis.Cluster.NumberOfNonTaintedNodes)
+	}
+	// 8 - 3.5 CPUs left over a node of 4 CPUs
+	if analysis.Cluster.ExcessNodes != 1.125 {
+		t.Errorf("Expected 1.125 excess nodes, got %v", analysis.Cluster.ExcessNodes)
+	}
+}
+
+// TestAnalyzeAllNodesTainted tests that no candidate is picked when every node is tainted
+func TestAnalyzeAllNodesTainted(t *testing.T) {
+	n := testNode("tainted", "4", "8Gi", true)
+	analysis := Analyze(map[string]types.NodeManifest{n.Name: types.NewNodeManifest(n)}, map[string]types.PodManifest{})
+
+	if analysis.Candidate != nil || analysis.CandidateErr == nil {
+		t.Errorf("Expected no candidate, got %+v", analysis.Candidate)
+	}
+}
+
+// TestCalculationLoopInterval tests that the loop beats once per interval and stops with the controller
+func TestCalculationLoopInterval(t *testing.T) {
+	fc := clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+	stopCh := make(chan struct{})
+	c := &WorkloadsController{
+		d:            drainer.NewAPICordonDrainer(nil, nil),
+		podsMap:      make(map[string]types.PodManifest),
+		nodesMap:     make(map[string]types.NodeManifest),
+		api:          newAPIState(DefaultHistorySize),
+		health:       supervisor.NewHealth(fc, time.Minute),
+		clock:        fc,
+		loopInterval: time.Minute,
+		stopCh:       stopCh,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.RunCalculationLoop()
+	}()
+
+	for i := 0; i < 3; i++ {
+		awaitWaiters(t, fc)
+		if heartbeat := c.health.Heartbeat(); !heartbeat.Equal(fc.Now()) {
+			t.Fatalf("Expected a heartbeat at %v, got %v", fc.Now(), heartbeat)
+		}
+		fc.Step(time.Minute)
+	}
+
+	awaitWaiters(t, fc)
+	close(stopCh)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Calculation loop didn't stop")
+	}
+}
+
+func awaitWaiters(t *testing.T, fc *clocktesting.FakeClock) {
+	for start := time.Now(); !fc.HasWaiters(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("Nothing is waiting on the clock")
+		}
+	}
+}
+
+// TestRunWithBackoff tests that a stopped component is restarted with a growing backoff that
+// starts over once the component ran for long enough
+func TestRunWithBackoff(t *testing.T) {
+	fc := clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+	stopCh := make(chan struct{})
+	runs, longRuns := make(chan struct{}), make(chan bool)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runWithBackoff("test", func() error {
+			runs <- struct{}{}
+			if <-longRuns {
+				<-fc.After(serverBackoffReset)
+			}
+			return errors.New("stopped")
+		}, fc, stopCh)
+	}()
+	run := func(long bool) {
+		select {
+		case <-runs:
+			longRuns <- long
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Component not restarted at %v", fc.Now())
+		}
+	}
+
+	run(false)
+	awaitWaiters(t, fc)
+	fc.Step(1100 * time.Millisecond)
+	run(false)
+	awaitWaiters(t, fc)
+	fc.Step(1500 * time.Millisecond)
+	if !fc.HasWaiters() {
+		t.Fatalf("Expected the backoff to grow")
+	}
+	fc.Step(700 * time.Millisecond)
+
+	run(true)
+	awaitWaiters(t, fc)
+	fc.Step(serverBackoffReset)
+	awaitWaiters(t, fc)
+	fc.Step(1100 * time.Millisecond)
+	run(false)
+
+	close(stopCh)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Component restarted after the stop")
+	}
+}
+
+// TestAPICheck tests that the API server is probed at most once per interval
+func TestAPICheck(t *testing.T) {
+	fc := clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+	probes := 0
+	api := &apiCheck{clock: fc, probe: func(ctx context.Context) error {
+		probes++
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("Expected the probe to time out")
+		}
+		return errors.New("unreachable")
+	}}
+
+	for i := 0; i < 3; i++ {
+		if err := api.check(); err == nil {
+			t.Errorf("Expected the probe error")
+		}
+	}
+	if probes != 1 {
+		t.Errorf("Expected a single probe within the interval, got %d", probes)
+	}
+	fc.Step(apiCheckInterval)
+	_ = api.check()
+	if probes != 2 {
+		t.Errorf("Expected a probe once the interval passed, got %d", probes)
+	}
+}
+
+// TestAnalyzeCountsUnschedulablePods tests that only pending pods the scheduler found no node for are counted
+func TestAnalyzeCountsUnschedulablePods(t *testing.T) {
+	n := testNode("node", "4", "8Gi", false)
+	unschedulable := testPod("unschedulable", "", "1", "1Gi")
+	unschedulable.Status = v1.PodStatus{Phase: v1.PodPending, Conditions: []v1.PodCondition{
+		{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable}}}
+	waiting := testPod("waiting", "", "1", "1Gi")
+	waiting.Status = v1.PodStatus{Phase: v1.PodPending}
+	running := testPod("running", "node", "1", "1Gi")
+
+	podsMap := map[string]types.PodManifest{}
+	for _, p := range []*v1.Pod{unschedulable, waiting, running} {
+		podsMap[p.Name] = types.NewPodManifest(p)
+	}
+	analysis := Analyze(map[string]types.NodeManifest{n.Name: types.NewNodeManifest(n)}, podsMap)
+
+	if analysis.Cluster.NumberOfPendingPods != 1 {
+		t.Errorf("Expected 1 unschedulable pod, got %d", analysis.Cluster.NumberOfPendingPods)
+	}
+}
+
+// TestNodeReadiness tests that not ready nodes are counted and that recovering nodes restart the ready cooldown
+func TestNodeReadiness(t *testing.T) {
+	c := &WorkloadsController{
+		d:        drainer.NewAPICordonDrainer(nil, nil),
+		podsMap:  make(map[string]types.PodManifest),
+		nodesMap: make(map[string]types.NodeManifest),
+	}
+	joined := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	withReady := func(n *v1.Node, status v1.ConditionStatus, since time.Time) *v1.Node {
+		n = n.DeepCopy()
+		n.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: status, LastTransitionTime: meta_v1.NewTime(since)}}
+		return n
+	}
+
+	ready := withReady(testNode("node", "4", "8Gi", false), v1.ConditionTrue, joined)
+	c.addNode(ready)
+	if !c.d.LastNodeReady.Equal(joined) {
+		t.Errorf("Expected the node ready at %v, got %v", joined, c.d.LastNodeReady)
+	}
+
+	notReady := withReady(ready, v1.ConditionFalse, joined.Add(time.Hour))
+	c.updateNode(ready, notReady)
+	if analysis := Analyze(c.nodesMap, c.podsMap); analysis.Cluster.NumberOfNotReadyNodes != 1 {
+		t.Errorf("Expected 1 not ready node, got %d", analysis.Cluster.NumberOfNotReadyNodes)
+	}
+
+	recovered := withReady(notReady, v1.ConditionTrue, joined.Add(2*time.Hour))
+	c.updateNode(notReady, recovered)
+	if analysis := Analyze(c.nodesMap, c.podsMap); analysis.Cluster.NumberOfNotReadyNodes != 0 {
+		t.Errorf("Expected no not ready node, got %d", analysis.Cluster.NumberOfNotReadyNodes)
+	}
+	if !c.d.LastNodeReady.Equal(joined.Add(2 * time.Hour)) {
+		t.Errorf("Expected the node ready at %v, got %v", joined.Add(2*time.Hour), c.d.LastNodeReady)
+	}
+}
+
+// TestReadiness tests that the controller is ready once the informers synced and the settings are loaded
+func TestReadiness(t *testing.T) {
+	awaitReady := func(c *WorkloadsController, component string, healthy bool) supervisor.Status {
+		var status supervisor.Status
+		for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+			status = c.health.Status()
+			if state, ok := status.Components[component]; ok && state.Healthy == healthy && state.Message != supervisor.ErrNotReported.Error() {
+				return status
+			}
+		}
+		t.Fatalf("Component %s not reported healthy=%v: %+v", component, healthy, status)
+		return status
+	}
+
+	c := testController(fake.NewSimpleClientset())
+	if status := awaitReady(c, componentConfig, true); !status.Ready || status.DrainerPhase != drainer.PhaseIdle {
+		t.Errorf("Expected the controller to be ready with the default settings, got %+v", status)
+	}
+
+	invalid := &v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: DefaultConfigMapName, Namespace: "default"},
+		Data: map[string]string{"time_gap": "ten"}}
+	c = testController(fake.NewSimpleClientset(invalid))
+	if status := awaitReady(c, componentConfig, false); status.Ready {
+		t.Errorf("Expected the controller not to be ready with invalid settings, got %+v", status)
+	}
+}
+
+// TestAnalyzeExclusions tests that excluded pods are left out of the utilization and keep their node from being picked
+func TestAnalyzeExclusions(t *testing.T) {
+	nodesMap := map[string]types.NodeManifest{}
+	for _, n := range []*v1.Node{testNode("busy", "4", "8Gi", false), testNode("idle", "4", "8Gi", false)} {
+		nodesMap[n.Name] = types.NewNodeManifest(n)
+	}
+	monitoring := testPod("prometheus", "idle", "1", "1Gi")
+	monitoring.Namespace = "monitoring"
+	podsMap := map[string]types.PodManifest{}
+	for _, p := range []*v1.Pod{testPod("a", "busy", "2", "4Gi"), testPod("b", "idle", "500m", "1Gi"), monitoring} {
+		podsMap[p.Namespace+"/"+p.Name] = types.NewPodManifest(p)
+	}
+	exclusions := &types.Exclusions{DeniedNamespaces: []string{"monitoring"}, ExcludeFromUtilization: true}
+	if err := exclusions.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	analysis := AnalyzeContext(context.Background(), nodesMap, podsMap, exclusions)
+	idle := analysis.Nodes["idle"]
+	if cpu := idle.TotalPodsRequests.ReqCPU.MilliValue(); cpu != 500 {
+		t.Errorf("Expected the monitoring pod to be left out of the requests, got %dm", cpu)
+	}
+	if analysis.Candidate == nil || analysis.Candidate.Node.Name != "idle" {
+		t.Fatalf("Expected idle to be the candidate, got %+v (%v)", analysis.Candidate, analysis.CandidateErr)
+	}
+
+	exclusions.BlockDrain = true
+	analysis = AnalyzeContext(context.Background(), nodesMap, podsMap, exclusions)
+	if analysis.Candidate == nil || analysis.Candidate.Node.Name != "busy" {
+		t.Fatalf("Expected busy to be the candidate, got %+v (%v)", analysis.Candidate, analysis.CandidateErr)
+	}
+	if blocker := analysis.Nodes["idle"].DrainBlockedBy; blocker != "the excluded pod monitoring/prometheus" {
+		t.Errorf("Expected idle to be blocked by the monitoring pod, got %q", blocker)
+	}
+}
+
+// TestAnalyzePriorityThreshold tests that nodes running pods of too high a priority are never picked,
+// unless the pods belong to a DaemonSet
+func TestAnalyzePriorityThreshold(t *testing.T) {
+	nodesMap := map[string]types.NodeManifest{}
+	for _, n := range []*v1.Node{testNode("busy", "4", "8Gi", false), testNode("idle", "4", "8Gi", false)} {
+		nodesMap[n.Name] = types.NewNodeManifest(n)
+	}
+	critical, proxy := testPod("critical", "idle", "100m", "128Mi"), testPod("proxy", "busy", "100m", "128Mi")
+	nodeCritical, isController := int32(2000001000), true
+	critical.Spec.Priority, proxy.Spec.Priority = &nodeCritical, &nodeCritical
+	proxy.OwnerReferences = []meta_v1.OwnerReference{{Kind: "DaemonSet", Name: "proxy", Controller: &isController}}
+	podsMap := map[string]types.PodManifest{}
+	for _, p := range []*v1.Pod{testPod("a", "busy", "2", "4Gi"), critical, proxy} {
+		podsMap[p.Name] = types.NewPodManifest(p)
+	}
+	threshold := int32(1000000000)
+	analysis := AnalyzeContext(context.Background(), nodesMap, podsMap, &types.Exclusions{PriorityThreshold: &threshold})
+
+	if analysis.Candidate == nil || analysis.Candidate.Node.Name != "busy" {
+		t.Fatalf("Expected busy to be the candidate, got %+v (%v)", analysis.Candidate, analysis.CandidateErr)
+	}
+	if blocker := analysis.Nodes["idle"].DrainBlockedBy; blocker != "the pod default/critical of priority 2000001000, the threshold is 1000000000" {
+		t.Errorf("Unexpected drain blocker %q", blocker)
+	}
+}
+
+type recordingAuditor struct {
+	records []audit.Record
+}
+
+func (r *recordingAuditor) Audit(record audit.Record) {
+	r.records = append(r.records, record)
+}
+
+// TestAuditDecisionChanges tests that refused decisions are only audited when they change, allowed ones always
+func TestAuditDecisionChanges(t *testing.T) {
+	auditor := &recordingAuditor{}
+	c := &WorkloadsController{d: drainer.NewAPICordonDrainer(nil, nil), auditor: auditor}
+	analysis := &Analysis{Candidate: &types.NodeManifest{Node: &v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: "node"}}}}
+	now := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, decision := range []drainer.DrainDecision{
+		{Reason: "waiting for default grace period for another node drain, 120 seconds remaining"},
+		{Reason: "waiting for default grace period for another node drain, 60 seconds remaining"},
+		{Reason: "unable to scale down because 2 nodes are not ready"},
+		{Allowed: true, Reason: "all conditions passed"},
+		{Allowed: true, Reason: "all conditions passed"},
+	} {
+		decision := decision
+		c.auditDecision(now, analysis, &decision)
+	}
+	if len(auditor.records) != 4 {
+		t.Fatalf("Expected 4 decisions to be audited, got %+v", auditor.records)
+	}
+	if auditor.records[1].Reason != "unable to scale down because 2 nodes are not ready" || auditor.records[3].Result != audit.ResultAllowed {
+		t.Errorf("Unexpected decisions %+v", auditor.records)
+	}
+}
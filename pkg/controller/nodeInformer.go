@@ -1,9 +1,6 @@
 package controller
 
 import (
-	"errors"
-
-	"github.com/SAP/node-refiner/pkg/common"
 	"github.com/SAP/node-refiner/pkg/types"
 
 	"go.uber.org/zap"
@@ -24,17 +21,14 @@ func (c *WorkloadsController) AddNodeEventHandler() {
 // addNode notifies informer that a node is added to the cluster
 func (c *WorkloadsController) addNode(obj interface{}) {
 	node := obj.(*corev1.Node)
-	c.nodesMap[node.Name] = types.NodeManifest{
-		Node:         node,
-		Metrics:      types.CreateNodeMetricsFromNodeObj(node),
-		Pods:         make([]*types.PodManifest, 10),
-		NumberOfPods: 0,
-	}
+	c.mu.Lock()
+	c.nodesMap[node.Name] = types.NewNodeManifest(node)
+	c.mu.Unlock()
 	nodeTime := node.CreationTimestamp.Time
-	if c.d.LastNodeAddition.Before(nodeTime) {
+	if c.d.SetLastNodeAddition(nodeTime) {
 		zap.S().Infow("Updated the newest node addition time", "node", node.Name, "creation timestamp", nodeTime)
-		c.d.LastNodeAddition = nodeTime
 	}
+	c.updateLastNodeReady(node)
 }
 
 // updateNode notifies informer that a node is updated in the cluster
@@ -44,15 +38,26 @@ func (c *WorkloadsController) updateNode(old, new interface{}) {
 	newNode := new.(*corev1.Node)
 
 	if compareNodes(oldNode, newNode) {
+		c.mu.Lock()
 		delete(c.nodesMap, oldNode.Name)
-		c.nodesMap[newNode.Name] = types.NodeManifest{
-			Node:         newNode,
-			Metrics:      types.CreateNodeMetricsFromNodeObj(newNode),
-			Pods:         make([]*types.PodManifest, 10),
-			NumberOfPods: 0,
-		}
+		c.nodesMap[newNode.Name] = types.NewNodeManifest(newNode)
+		c.mu.Unlock()
 		zap.S().Infof("Update took place for node %v, now node %v", oldNode.Name, newNode.Name)
 	}
+	if oldNode.Spec.Unschedulable && !newNode.Spec.Unschedulable {
+		// A drained node given back to the pods saves nothing anymore
+		c.d.StopSaving(newNode.Name)
+	}
+	c.updateLastNodeReady(newNode)
+}
+
+// updateLastNodeReady postpones scale downs when the node recently became ready, whether it
+// just joined the cluster or recovered
+func (c *WorkloadsController) updateLastNodeReady(node *corev1.Node) {
+	since, ready := types.NodeReadySince(node)
+	if ready && c.d.SetLastNodeReady(since) {
+		zap.S().Infow("Updated the latest time a node became ready", "node", node.Name, "ready since", since)
+	}
 }
 
 // deleteNode notifies informer that a node is deleted in the cluster
@@ -60,6 +65,8 @@ func (c *WorkloadsController) deleteNode(obj interface{}) {
 	// Cast the obj as Node
 	node := obj.(*corev1.Node)
 	zap.S().Infow("node was deleted", "node", node.Name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	delete(c.nodesMap, node.Name)
 }
 
@@ -79,73 +86,10 @@ func compareNodes(oldNode *corev1.Node, newNode *corev1.Node) bool {
 		return true
 	}
 
-	return false
-}
-
-// getRandomNode picks one of the non-tainted nodes randomly
-func (c *WorkloadsController) getRandomNode() (*types.NodeManifest, error) {
-	var res types.NodeManifest
-	found := false
-
-	if len(c.nodesMap) == 0 {
-		return &res, errors.New("couldn't find any node manifests in this map")
-	}
-
-	for _, nm := range c.nodesMap {
-		if !common.CheckForTaints(nm.Node) {
-			res = nm
-			found = true
-		}
-		if found {
-			break
-		}
-	}
-	if !found {
-		return &res, errors.New("all nodes are tainted, unable to find any node to drain")
-	}
-
-	return &res, nil
-}
-
-// getNodeToDrain get the least utilized node, potentially to drain it
-func (c *WorkloadsController) getNodeToDrain() (*types.NodeManifest, error) {
-	nmMin, err := c.getRandomNode()
-	if err != nil {
-		zap.S().Warnw("unable to proceed with picking a node", "error", err)
-		return nmMin, err
-	}
-
-	for i := range c.nodesMap {
-		nm := c.nodesMap[i]
-		if !common.CheckForTaints(nm.Node) {
-			if nm.Utilization.Score < nmMin.Utilization.Score {
-				nmMin = &nm
-			}
-		}
-	}
-	return nmMin, nil
-}
-
-// addPodsToNodes assigns Pods to the corresponding node in the central nodesMap
-func (c *WorkloadsController) addPodsToNodes() {
-	for i := range c.podsMap {
-		pm := c.podsMap[i]
-		c.addIfNodeExists(&pm)
+	// The node became ready or not ready
+	if types.IsNodeReady(oldNode) != types.IsNodeReady(newNode) {
+		return true
 	}
-}
 
-// clearPodsList clears all the Pods in the NodeManifest object
-func (c *WorkloadsController) clearPodsList() {
-	for i, val := range c.nodesMap {
-		val.Pods = make([]*types.PodManifest, 0)
-		c.nodesMap[i] = val
-	}
-}
-
-// addIfNodeExists add the pod if and only if It was already added in the central nodesMap (avoid data race)
-func (c *WorkloadsController) addIfNodeExists(podManifest *types.PodManifest) {
-	if val, ok := c.nodesMap[podManifest.Pod.Spec.NodeName]; ok {
-		val.Pods = append(val.Pods, podManifest)
-		c.nodesMap[podManifest.Pod.Spec.NodeName] = val
-	}
+	return false
 }
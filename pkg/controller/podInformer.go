@@ -21,7 +21,11 @@ func (c *WorkloadsController) addPod(obj interface{}) {
 	// Cast the obj as Pods
 	pod := obj.(*corev1.Pod)
 	pm := types.NewPodManifest(pod)
+	c.mu.Lock()
 	c.podsMap[pod.Name] = pm
+	c.mu.Unlock()
+	// Pods listed when the informer starts only count as churn if they were created within the window
+	c.d.RecordPodChurn(pod.CreationTimestamp.Time)
 }
 
 // updatePod notifies informer that a pod is updated
@@ -30,8 +34,10 @@ func (c *WorkloadsController) updatePod(old, new interface{}) {
 	oldPod := old.(*corev1.Pod)
 	newPod := new.(*corev1.Pod)
 	if comparePods(oldPod, newPod) {
-		delete(c.podsMap, oldPod.Name)
 		podManifest := types.NewPodManifest(newPod)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.podsMap, oldPod.Name)
 		c.podsMap[newPod.Name] = podManifest
 	}
 }
@@ -40,7 +46,10 @@ func (c *WorkloadsController) updatePod(old, new interface{}) {
 func (c *WorkloadsController) deletePod(obj interface{}) {
 	// Cast the obj as Pods
 	pod := obj.(*corev1.Pod)
+	c.mu.Lock()
 	delete(c.podsMap, pod.Name)
+	c.mu.Unlock()
+	c.d.RecordPodChurn(c.clock.Now())
 }
 
 // comparePods compares the application relevant changes and send a bool value to act upon them if found
@@ -53,5 +62,9 @@ func comparePods(oldPod *corev1.Pod, newPod *corev1.Pod) bool {
 	if oldPod.Spec.NodeName != newPod.Spec.NodeName {
 		return true
 	}
+	// The scheduler gave up on or found a node for the pod
+	if types.IsUnschedulablePod(oldPod) != types.IsUnschedulablePod(newPod) {
+		return true
+	}
 	return false
 }
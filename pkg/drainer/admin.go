@@ -0,0 +1,86 @@
+package drainer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	internaltypes "github.com/SAP/node-refiner/pkg/types"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ErrNoDrainInProgress is returned when there is no drain to abort
+var ErrNoDrainInProgress = errors.New("no drain in progress")
+
+// Pause stops the drainer from starting scale downs until it is resumed, the drains in
+// progress go on. The drainer is resumed when the controller restarts
+func (d *APICordonDrainer) Pause() {
+	d.setPaused(true)
+}
+
+// Resume lets a paused drainer start scale downs again
+func (d *APICordonDrainer) Resume() {
+	d.setPaused(false)
+}
+
+func (d *APICordonDrainer) setPaused(paused bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.paused != paused {
+		zap.S().Infow("Changing whether the drainer is paused", "from", d.paused, "to", paused)
+		d.paused = paused
+	}
+	if d.s != nil {
+		value := 0.0
+		if paused {
+			value = 1
+		}
+		d.s.DrainerMetrics.Paused.Set(value)
+	}
+}
+
+// Paused reports whether the drainer was paused
+func (d *APICordonDrainer) Paused() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.paused
+}
+
+// Abort cancels the drains in progress and uncordons their nodes, it returns the nodes
+// whose drain was aborted. The evictions already accepted by the API server are not reverted
+func (d *APICordonDrainer) Abort() ([]string, error) {
+	d.mu.Lock()
+	nodes := make([]string, 0, len(d.drains))
+	for nodeName, cancel := range d.drains {
+		zap.S().Infow("Aborting the drain of the node", "node", nodeName)
+		cancel()
+		nodes = append(nodes, nodeName)
+	}
+	d.mu.Unlock()
+	if len(nodes) == 0 {
+		return nil, ErrNoDrainInProgress
+	}
+	sort.Strings(nodes)
+
+	var failed []string
+	for _, nodeName := range nodes {
+		err := d.Uncordon(nodeName)
+		if err != nil && !errors.Is(err, ErrNodeNotFound) {
+			failed = append(failed, fmt.Sprintf("%s: %v", nodeName, err))
+		}
+	}
+	if len(failed) > 0 {
+		return nodes, errors.Errorf("cannot uncordon the aborted nodes, %s", strings.Join(failed, ", "))
+	}
+	return nodes, nil
+}
+
+// RequestDrain scales the node down like the calculation loop does for its candidate, if
+// no scale down is in progress and the drain checks pass. The caller checks that the node
+// itself may be drained
+func (d *APICordonDrainer) RequestDrain(nodeName string, clusterManifest *internaltypes.ClusterManifest) DrainDecision {
+	zap.S().Infow("Drain of the node requested", "node", nodeName)
+	return d.AttemptDrain(nodeName, clusterManifest)
+}
@@ -0,0 +1,47 @@
+package drainer
+
+import (
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/audit"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SetAuditor sets who records the scale downs
+func (d *APICordonDrainer) SetAuditor(a audit.Auditor) {
+	d.auditor = a
+}
+
+// auditScaleDown records the scale down of the node with the pods it evicted
+func (d *APICordonDrainer) auditScaleDown(nodeName string, started time.Time, evicted []*v1.Pod, err error) {
+	if d.auditor == nil {
+		return
+	}
+	now := d.clock.Now()
+	record := audit.Record{
+		Time:         now,
+		Kind:         audit.KindScaleDown,
+		Node:         nodeName,
+		SettingsHash: d.SettingsHash(),
+		Result:       audit.ResultSucceeded,
+		Duration:     now.Sub(started),
+	}
+	for _, p := range evicted {
+		record.Pods = append(record.Pods, auditPod(p))
+	}
+	if err != nil {
+		record.Result = audit.ResultFailed
+		record.Error = err.Error()
+	}
+	d.auditor.Audit(record)
+}
+
+func auditPod(p *v1.Pod) audit.Pod {
+	pod := audit.Pod{Namespace: p.Namespace, Name: p.Name}
+	if ref := metav1.GetControllerOf(p); ref != nil {
+		pod.Owner = ref.Kind + "/" + ref.Name
+	}
+	return pod
+}
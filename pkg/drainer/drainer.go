@@ -3,34 +3,67 @@ package drainer
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/SAP/node-refiner/pkg/audit"
+	"github.com/SAP/node-refiner/pkg/common"
+	"github.com/SAP/node-refiner/pkg/notifier"
+	"github.com/SAP/node-refiner/pkg/schedule"
 	"github.com/SAP/node-refiner/pkg/supervisor"
+	"github.com/SAP/node-refiner/pkg/tracing"
 	internaltypes "github.com/SAP/node-refiner/pkg/types"
 
 	"github.com/pkg/errors"
 
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
-	policy "k8s.io/api/policy/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
 )
 
 // Default drainer settings.
 const (
 	DefaultMaxGracePeriod   = 8 * time.Minute
 	DefaultEvictionOverhead = 30 * time.Second
+	DefaultEvictionRetry    = 5 * time.Second
+	DefaultEvictionRetryCap = 1 * time.Minute
+	DefaultPDBWaitTimeout   = 10 * time.Minute
+	// Deleting pods whose eviction is refused is disabled by default
+	DefaultDeleteFallbackTimeout time.Duration = 0
+	DefaultVerifyTimeout                       = 10 * time.Minute
+	DefaultVerifyReady                         = false
+	DefaultVerifyPoll                          = 5 * time.Second
+	DefaultDeletionPoll                        = 1 * time.Second
+
+	DefaultMaxConcurrentEvictions = 5
+	DefaultWaitForReplacements    = false
+	DefaultReplacementTimeout     = 5 * time.Minute
+	DefaultDrainTimeout           = 30 * time.Minute
 
 	DefaultTimeGap                      = 10 * time.Minute
 	DefaultMinimumTimeSinceLastAddition = 60 * time.Minute
+	DefaultMinimumTimeSinceNodeReady    = 30 * time.Minute
+
+	// Scale downs wait for the cooldown after DefaultPodChurnThreshold pods were created
+	// or deleted within DefaultPodChurnWindow
+	DefaultPodChurnThreshold = 50
+	DefaultPodChurnWindow    = 5 * time.Minute
+	DefaultPodChurnCooldown  = 15 * time.Minute
 
 	DefaultMinimumNodes           = 3
 	DefaultMinimumNonTaintedNodes = 3
@@ -38,6 +71,36 @@ const (
 	DefaultEnabled                = true
 )
 
+// Phases of the drainer reported by Phase
+const (
+	PhaseIdle        = "idle"
+	PhaseDisabled    = "disabled"
+	PhasePaused      = "paused"
+	PhaseCordoning   = "cordoning"
+	PhaseDraining    = "draining"
+	PhaseVerifying   = "verifying"
+	PhaseUncordoning = "uncordoning"
+)
+
+// ErrNodeNotFound is returned when the node to act upon no longer exists,
+// for example because it was deleted while being drained
+var ErrNodeNotFound = errors.New("node not found")
+
+// ErrEvictionBlocked is returned when a pod disruption budget refused the eviction
+// of a pod for longer than the PDB wait timeout
+var ErrEvictionBlocked = errors.New("eviction blocked by a pod disruption budget")
+
+// ErrScaleDownInProgress is returned when a scale down is requested while another one is running
+var ErrScaleDownInProgress = errors.New("a scale down is in progress")
+
+// ErrScheduleBlocked is returned when a blocked window or a freeze period starts, or an allowed
+// window ends, during a drain. The pods left on the node are not evicted
+var ErrScheduleBlocked = errors.New("drains are not allowed by the schedule")
+
+// ErrDrainBlocked is returned when the node to drain runs a pod the exclusions keep from being
+// evicted, an excluded pod or a pod of too high a priority
+var ErrDrainBlocked = errors.New("node runs a pod keeping it from being drained")
+
 // Cordoner cordons/uncordons nodes.
 type Cordoner interface {
 	// Cordon the supplied node. Marks it unschedulable for new pods.
@@ -61,22 +124,77 @@ type CordonDrainer interface {
 
 // APICordonDrainer drains Kubernetes nodes via the Kubernetes API.
 type APICordonDrainer struct {
-	c kubernetes.Interface
-	s *supervisor.Supervisor
+	c     kubernetes.Interface
+	s     *supervisor.Supervisor
+	clock clock.Clock
+
+	// Told about the scale downs, with the cluster utilization from clusterSource
+	notifier      notifier.Notifier
+	clusterSource func() internaltypes.ClusterManifest
+	auditor       audit.Auditor
+
+	// Nodes cordoned by the scale downs in progress, most recent last, and the drains in progress
+	mu       sync.Mutex
+	cordoned []string
+	drains   map[string]context.CancelFunc
+	// Creation and deletion times of pods within the pod churn window, guarded by mu
+	podEvents []time.Time
+	// Step of the running scale down, empty when idle, guarded by mu
+	phase string
+	// Latest scale down, guarded by mu
+	lastAction Action
+	// Scale downs are not started while paused, guarded by mu
+	paused bool
+	// Prices of the nodes, the savings of the drained nodes not given back to the pods and
+	// the total saved by the others, guarded by mu
+	prices      *internaltypes.PriceTable
+	savings     []Saving
+	savedBefore float64
+	// Pods keeping their nodes from being drained, guarded by mu
+	exclusions *internaltypes.Exclusions
 
-	// Current State
-	LastNodeAddition time.Time
-	LastScaleDown    time.Time
+	// Current State, guarded by mu and set through the setters
+	LastNodeAddition       time.Time
+	LastNodeReady          time.Time
+	LastPodChurn           time.Time
+	LastScaleDown          time.Time
+	LastFailedScaleDown    time.Time
+	LastFailedScaleDownErr error
 
-	// Settings
+	// Settings, guarded by mu. The drains work from a copy taken with currentSettings
+	settings drainSettings
+}
+
+// drainSettings are the settings of the drainer, updated from the ConfigMap
+type drainSettings struct {
 	enabled                      bool
 	maxGracePeriod               time.Duration
 	evictionHeadroom             time.Duration
 	timeGap                      time.Duration
 	minimumTimeSinceLastAddition time.Duration
+	minimumTimeSinceNodeReady    time.Duration
+	podChurnThreshold            int
+	podChurnWindow               time.Duration
+	podChurnCooldown             time.Duration
 	minimumNodes                 int
 	minimumNonTaintedNodes       int
 	excessNodesThreshold         float64
+	maxConcurrentEvictions       int
+	waitForReplacements          bool
+	replacementTimeout           time.Duration
+	drainTimeout                 time.Duration
+	pdbWaitTimeout               time.Duration
+	deleteFallbackTimeout        time.Duration
+	verifyTimeout                time.Duration
+	verifyReady                  bool
+	evictionVersion              schema.GroupVersion
+	schedule                     *schedule.Schedule
+	scheduleSettings             scheduleSettings
+}
+
+// scheduleSettings are the ConfigMap values the drain schedule is parsed from
+type scheduleSettings struct {
+	timezone, allowedWindows, blockedWindows, freezePeriods string
 }
 
 // NodeDesiredState to set a future state for the unschedulable node flag
@@ -89,82 +207,267 @@ type NodeDesiredState struct {
 // the Kubernetes API.
 func NewAPICordonDrainer(c kubernetes.Interface, supervisor *supervisor.Supervisor) *APICordonDrainer {
 	d := &APICordonDrainer{
-		c: c,
-		s: supervisor,
+		c:     c,
+		s:     supervisor,
+		clock: clock.RealClock{},
 
 		// Setup Initial Settings
-		enabled:                      DefaultEnabled,
-		maxGracePeriod:               DefaultMaxGracePeriod,
-		evictionHeadroom:             DefaultEvictionOverhead,
-		timeGap:                      DefaultTimeGap,
-		minimumTimeSinceLastAddition: DefaultMinimumTimeSinceLastAddition,
-		minimumNodes:                 DefaultMinimumNodes,
-		minimumNonTaintedNodes:       DefaultMinimumNonTaintedNodes,
-		excessNodesThreshold:         DefaultExcessNodesThreshold,
+		settings: drainSettings{
+			enabled:                      DefaultEnabled,
+			maxGracePeriod:               DefaultMaxGracePeriod,
+			evictionHeadroom:             DefaultEvictionOverhead,
+			timeGap:                      DefaultTimeGap,
+			minimumTimeSinceLastAddition: DefaultMinimumTimeSinceLastAddition,
+			minimumTimeSinceNodeReady:    DefaultMinimumTimeSinceNodeReady,
+			podChurnThreshold:            DefaultPodChurnThreshold,
+			podChurnWindow:               DefaultPodChurnWindow,
+			podChurnCooldown:             DefaultPodChurnCooldown,
+			minimumNodes:                 DefaultMinimumNodes,
+			minimumNonTaintedNodes:       DefaultMinimumNonTaintedNodes,
+			excessNodesThreshold:         DefaultExcessNodesThreshold,
+			maxConcurrentEvictions:       DefaultMaxConcurrentEvictions,
+			waitForReplacements:          DefaultWaitForReplacements,
+			replacementTimeout:           DefaultReplacementTimeout,
+			drainTimeout:                 DefaultDrainTimeout,
+			pdbWaitTimeout:               DefaultPDBWaitTimeout,
+			deleteFallbackTimeout:        DefaultDeleteFallbackTimeout,
+			verifyTimeout:                DefaultVerifyTimeout,
+			verifyReady:                  DefaultVerifyReady,
+			evictionVersion:              EvictionV1beta1,
+		},
 	}
 	return d
 }
 
-// AttemptDrain runs multiple checks to ensure that the drain procedure satisfies all the requirements
-func (d *APICordonDrainer) AttemptDrain(nodeToDrain string, clusterManifest *internaltypes.ClusterManifest) {
-	if !d.enabled {
-		zap.S().Infow("Drainer", "state", "drainer is disabled based on the provided configuration")
-		return
+// DrainDecision is the outcome of the checks run before a scale down
+type DrainDecision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
+
+// EvaluateDrain runs multiple checks to decide whether the drain procedure satisfies all the requirements
+func (d *APICordonDrainer) EvaluateDrain(clusterManifest *internaltypes.ClusterManifest) DrainDecision {
+	return d.EvaluateDrainAt(d.clock.Now(), clusterManifest)
+}
+
+// EvaluateDrainAt runs the drain checks as if the current time was now
+func (d *APICordonDrainer) EvaluateDrainAt(now time.Time, clusterManifest *internaltypes.ClusterManifest) DrainDecision {
+	d.mu.Lock()
+	settings, paused := d.settings, d.paused
+	lastNodeAddition, lastNodeReady, lastPodChurn, lastScaleDown := d.LastNodeAddition, d.LastNodeReady, d.LastPodChurn, d.LastScaleDown
+	d.mu.Unlock()
+
+	if !settings.enabled {
+		return DrainDecision{Reason: "drainer is disabled based on the provided configuration"}
 	}
-	if clusterManifest.ExcessNodes < d.excessNodesThreshold {
-		zap.S().Infow("Drainer", "state", "nothing to scale down, cluster has no excess resources")
-		return
+	if paused {
+		return DrainDecision{Reason: "drainer is paused by an administrator"}
+	}
+	if clusterManifest.ExcessNodes < settings.excessNodesThreshold {
+		return DrainDecision{Reason: fmt.Sprintf("nothing to scale down, cluster has %.2f excess nodes, threshold is %v",
+			clusterManifest.ExcessNodes, settings.excessNodesThreshold)}
 	}
 
-	if time.Since(d.LastNodeAddition) < d.minimumTimeSinceLastAddition {
-		remaining := int((d.minimumTimeSinceLastAddition - time.Since(d.LastNodeAddition)).Minutes())
-		zap.S().Infof("waiting for default time for scale down operations to start after adding a new node, time remaining %v minutes", remaining)
-		return
+	if blocked := settings.schedule.Blocked(now); blocked != "" {
+		next := "drains are not allowed within the next years"
+		if t, ok := settings.schedule.NextAllowed(now); ok {
+			next = fmt.Sprintf("next allowed at %s", t.Format(time.RFC3339))
+		}
+		return DrainDecision{Reason: fmt.Sprintf("drains are not allowed by the schedule, %s, %s", blocked, next)}
 	}
 
-	if time.Since(d.LastScaleDown) < d.timeGap {
-		remaining := d.timeGap - time.Since(d.LastScaleDown)
-		zap.S().Infof("Waiting for Default Grace Period for another Node Drain %v seconds remaining", int(remaining.Seconds()))
-		return
+	if clusterManifest.NumberOfPendingPods > 0 {
+		return DrainDecision{Reason: fmt.Sprintf("unable to scale down because %d pending pods don't fit on any node", clusterManifest.NumberOfPendingPods)}
 	}
 
-	if clusterManifest.NumberOfNodes < d.minimumNodes {
-		logMessage := fmt.Sprintf("unable to scale down because the cluster has less than %v nodes", d.minimumNodes)
-		zap.S().Infow("Drainer", "issue", logMessage)
-		return
+	if now.Sub(lastNodeAddition) < settings.minimumTimeSinceLastAddition {
+		remaining := int((settings.minimumTimeSinceLastAddition - now.Sub(lastNodeAddition)).Minutes())
+		return DrainDecision{Reason: fmt.Sprintf("waiting for default time for scale down operations to start after adding a new node, time remaining %v minutes", remaining)}
 	}
 
-	if clusterManifest.NumberOfNonTaintedNodes < d.minimumNonTaintedNodes {
-		logMessage := fmt.Sprintf("unable to scale down because the cluster has less than %v non tainted nodes", d.minimumNonTaintedNodes)
-		zap.S().Infow("Drainer", "issue", logMessage)
-		return
+	if clusterManifest.NumberOfNotReadyNodes > 0 {
+		return DrainDecision{Reason: fmt.Sprintf("unable to scale down because %d nodes are not ready", clusterManifest.NumberOfNotReadyNodes)}
+	}
+
+	if now.Sub(lastNodeReady) < settings.minimumTimeSinceNodeReady {
+		remaining := int((settings.minimumTimeSinceNodeReady - now.Sub(lastNodeReady)).Minutes())
+		return DrainDecision{Reason: fmt.Sprintf("waiting for the cluster to stabilize after a node became ready, time remaining %v minutes", remaining)}
+	}
+
+	if now.Sub(lastPodChurn) < settings.podChurnCooldown {
+		remaining := int((settings.podChurnCooldown - now.Sub(lastPodChurn)).Minutes())
+		return DrainDecision{Reason: fmt.Sprintf("waiting for the cluster to stabilize after a large number of pods were created or deleted, time remaining %v minutes", remaining)}
+	}
+
+	if now.Sub(lastScaleDown) < settings.timeGap {
+		remaining := settings.timeGap - now.Sub(lastScaleDown)
+		return DrainDecision{Reason: fmt.Sprintf("waiting for default grace period for another node drain, %v seconds remaining", int(remaining.Seconds()))}
+	}
+
+	if clusterManifest.NumberOfNodes < settings.minimumNodes {
+		return DrainDecision{Reason: fmt.Sprintf("unable to scale down because the cluster has less than %v nodes", settings.minimumNodes)}
+	}
+
+	if clusterManifest.NumberOfNonTaintedNodes < settings.minimumNonTaintedNodes {
+		return DrainDecision{Reason: fmt.Sprintf("unable to scale down because the cluster has less than %v non tainted nodes", settings.minimumNonTaintedNodes)}
+	}
+
+	return DrainDecision{Allowed: true, Reason: "all conditions passed"}
+}
+
+// AttemptDrain drains the node if the drain procedure satisfies all the requirements and no
+// scale down is in progress, returns the decision that was taken
+func (d *APICordonDrainer) AttemptDrain(nodeToDrain string, clusterManifest *internaltypes.ClusterManifest) DrainDecision {
+	now := d.clock.Now()
+	decision := d.EvaluateDrainAt(now, clusterManifest)
+	d.publishNextDrainWindow(now)
+	if !decision.Allowed {
+		zap.S().Infow("Drainer", "state", decision.Reason)
+		return decision
+	}
+
+	// The pods of the node are checked again, a pod keeping it from being drained may have been
+	// scheduled since the analysis, or the drain was requested on the admin API. A refused node
+	// neither uses up the time gap nor is reported as a scale down
+	if err := d.checkExclusions(nodeToDrain); err != nil {
+		zap.S().Infow("Drainer", "state", err.Error())
+		return DrainDecision{Reason: err.Error()}
 	}
 
-	// All conditions passed
-	go d.ScaleDown(nodeToDrain)
+	// All conditions passed, the scale down is started before returning so that no other one can
+	started, err := d.startAction(nodeToDrain)
+	if err != nil {
+		zap.S().Infow("Drainer", "state", err.Error())
+		return DrainDecision{Reason: err.Error()}
+	}
+	go d.scaleDown(nodeToDrain, started)
+	return decision
 }
 
-// ScaleDown records timestamp to the last scale down and initiates a node drain
+// ScaleDown records timestamp to the last scale down and initiates a node drain,
+// the node is uncordoned again if the drain fails. Nothing is done while another scale down
+// is in progress or when the node runs a pod the exclusions keep from being drained
 func (d *APICordonDrainer) ScaleDown(node string) {
-	d.LastScaleDown = time.Now()
-	zap.S().Infow("Cordoning Node", "node", node)
-	err := d.Cordon(node)
+	if err := d.checkExclusions(node); err != nil {
+		zap.S().Warnw("Not scaling down the node", "node", node, "error", err)
+		return
+	}
+	started, err := d.startAction(node)
 	if err != nil {
-		zap.S().Warnw("Couldn't Cordon Node", "node", node)
+		zap.S().Warnw("Not scaling down the node", "node", node, "error", err)
 		return
 	}
+	d.scaleDown(node, started)
+}
 
-	zap.S().Infow("Initiating a node drain", "node", node)
-	err = d.Drain(node)
+// scaleDown runs the scale down of the node started at the given time by startAction
+func (d *APICordonDrainer) scaleDown(node string, started time.Time) {
+	var err error
+	var evicted []*v1.Pod
+	ctx, span := tracing.Start(context.Background(), "scale-down", attribute.String("node", node))
+	log := tracing.Logger(ctx)
+	event := d.notifyStarted(node)
+	defer func() {
+		d.finishAction(err)
+		d.notifyFinished(event, len(evicted), err)
+		d.auditScaleDown(node, started, evicted, err)
+		span.SetAttributes(attribute.Int("pods.evicted", len(evicted)))
+		tracing.End(span, err)
+	}()
+	defer d.setPhase("")
+	log.Infow("Cordoning Node", "node", node)
+	_, cordonSpan := tracing.Start(ctx, "cordon", attribute.String("node", node))
+	err = d.Cordon(node)
+	tracing.End(cordonSpan, err)
 	if err != nil {
-		zap.S().Warnw("Couldn't drain node, will uncordon the node", "node", node)
-		err = d.Uncordon(node)
-		if err != nil {
-			zap.S().Warnw("Couldn't Uncordon node", "node", node)
+		log.Warnw("Couldn't Cordon Node", "node", node, "error", err)
+		d.recordFailedScaleDown(err)
+		return
+	}
+	d.trackCordoned(node)
+
+	log.Infow("Initiating a node drain", "node", node)
+	evicted, err = d.drain(ctx, node)
+	if err != nil {
+		log.Warnw("Couldn't drain node, will uncordon the node", "node", node, "error", err)
+		d.recordFailedScaleDown(err)
+		d.setPhase(PhaseUncordoning)
+		_, uncordonSpan := tracing.Start(ctx, "uncordon", attribute.String("node", node))
+		uncordonErr := d.Uncordon(node)
+		tracing.End(uncordonSpan, uncordonErr)
+		if errors.Is(uncordonErr, ErrNodeNotFound) {
+			log.Infow("Node is already gone, nothing to uncordon", "node", node)
+			return
+		}
+		if uncordonErr != nil {
+			log.Warnw("Couldn't Uncordon node", "node", node, "error", uncordonErr)
 			return
 		}
 		return
 	}
+	d.forgetCordoned(node)
+	d.recordSaving(node)
+}
+
+// startAction records the start of a scale down of the node and returns its start time, it
+// fails if another scale down is in progress. The phase stays set until the scale down is over
+func (d *APICordonDrainer) startAction(node string) (time.Time, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.phase != "" {
+		return time.Time{}, fmt.Errorf("%w, the drainer is %s", ErrScaleDownInProgress, d.phase)
+	}
+	d.phase = PhaseCordoning
+	d.LastScaleDown = d.clock.Now()
+	d.lastAction = Action{Node: node, Started: d.LastScaleDown}
+	return d.LastScaleDown, nil
+}
+
+// finishAction records the end of the latest scale down
+func (d *APICordonDrainer) finishAction(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	finished := d.clock.Now()
+	d.lastAction.Finished = &finished
+	if err != nil {
+		d.lastAction.Error = err.Error()
+	}
+}
+
+// Phase reports what the drainer is doing
+func (d *APICordonDrainer) Phase() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.phaseLocked()
+}
+
+func (d *APICordonDrainer) phaseLocked() string {
+	switch {
+	case d.phase != "":
+		return d.phase
+	case !d.settings.enabled:
+		return PhaseDisabled
+	case d.paused:
+		return PhasePaused
+	}
+	return PhaseIdle
+}
+
+func (d *APICordonDrainer) setPhase(phase string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.phase = phase
+}
+
+// recordFailedScaleDown remembers why the last scale down failed
+func (d *APICordonDrainer) recordFailedScaleDown(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.LastFailedScaleDown = d.clock.Now()
+	d.LastFailedScaleDownErr = err
+	if d.s != nil {
+		d.s.DrainerMetrics.ScaleDownsFailed.Inc()
+	}
 }
 
 // Cordon the supplied node. Marks it unschedulable for new pods.
@@ -198,16 +501,31 @@ func (d *APICordonDrainer) Uncordon(nodeName string) error {
 		unschedulable: false,
 	}
 
-	return d.AlterNodeState(nodeDesiredState)
+	err := d.AlterNodeState(nodeDesiredState)
+	if err == nil || errors.Is(err, ErrNodeNotFound) {
+		d.forgetCordoned(nodeName)
+	}
+	return err
 }
 
-// AlterNodeState from unschedulable to schedulable and vice-versa
+// AlterNodeState from unschedulable to schedulable and vice-versa.
+// Transient API errors are retried with a backoff, a node that no longer exists
+// is reported as ErrNodeNotFound
 func (d *APICordonDrainer) AlterNodeState(nodeDesiredState NodeDesiredState) error {
+	err := retry.OnError(retry.DefaultBackoff, common.IsTransientAPIError, func() error {
+		return d.alterNodeState(nodeDesiredState)
+	})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, nodeDesiredState.nodeName)
+	}
+	return err
+}
+
+func (d *APICordonDrainer) alterNodeState(nodeDesiredState NodeDesiredState) error {
 	ctx := d.getContext()
 	node, err := d.c.CoreV1().Nodes().Get(ctx, nodeDesiredState.nodeName, metav1.GetOptions{})
-
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
 	if node.Spec.Unschedulable == nodeDesiredState.unschedulable {
@@ -242,100 +560,349 @@ func (d *APICordonDrainer) AlterNodeState(nodeDesiredState NodeDesiredState) err
 	return nil
 }
 
-// Drain searches and evicts all pods contained in a node.
+// Drain searches and evicts all pods contained in a node. Pods are evicted in eviction order,
+// at most maxConcurrentEvictions at a time, optionally waiting for the replacement of a
+// replica to be ready before the next replica of the same controller is evicted. The drain
+// only succeeds once the replacements of the evicted pods are scheduled on other nodes.
 func (d *APICordonDrainer) Drain(nodeName string) error {
+	defer d.setPhase("")
+	_, err := d.drain(d.getContext(), nodeName)
+	return err
+}
+
+// evictionResult is the outcome of the eviction of a pod
+type evictionResult struct {
+	pod *v1.Pod
+	err error
+}
+
+// drain drains the node and returns the pods evicted, traced as a child of the span in ctx
+func (d *APICordonDrainer) drain(ctx context.Context, nodeName string) (evicted []*v1.Pod, err error) {
+	ctx, span := tracing.Start(ctx, "drain", attribute.String("node", nodeName))
+	defer func() { tracing.End(span, err) }()
 	// Increment Prometheus Metrics
 	if d.s != nil {
 		d.s.DrainerMetrics.NodesDrained.Inc()
 	}
+	d.setPhase(PhaseDraining)
 
 	pods, err := d.getPods(nodeName)
 	if err != nil {
-		return errors.Wrapf(err, "cannot get pods for node %s", nodeName)
+		return nil, errors.Wrapf(err, "cannot get pods for node %s", nodeName)
 	}
+	pods = evictionOrder(pods)
+	settings := d.currentSettings()
+	span.SetAttributes(attribute.Int("pods", len(pods)))
 
-	abort := make(chan struct{})
-	errs := make(chan error, 1)
-	for i := range pods {
-		go d.evict(&pods[i], abort, errs)
+	// Cancelling aborts the evictions that are still waiting in backoff, for
+	// their pod to be deleted or for their turn, and their API calls in flight.
+	// The drain returns once they are all done, none outlives it.
+	var evictions sync.WaitGroup
+	defer evictions.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer d.trackDrain(nodeName, cancel)()
+
+	var workloads map[types.UID]*workload
+	if settings.verifyTimeout > 0 {
+		if workloads, err = d.replacedWorkloads(ctx, pods); err != nil {
+			return nil, errors.Wrap(err, "cannot count the pods to be replaced")
+		}
 	}
-	// This will _eventually_ abort evictions. Evictions may spend up to
-	// d.deleteTimeout() in d.awaitDeletion(), or 5 seconds in backoff before
-	// noticing they've been aborted.
-	defer close(abort)
 
-	deadline := time.After(d.deleteTimeout())
+	results := make(chan evictionResult, len(pods))
+	evictions.Add(1)
+	go func() {
+		defer evictions.Done()
+		d.dispatchEvictions(ctx, pods, results, &evictions)
+	}()
+
+	deadline := d.clock.After(settings.drainTimeout)
 	for range pods {
 		select {
-		case err := <-errs:
-			if err != nil {
-				return errors.Wrap(err, "cannot evict all pods")
+		case result := <-results:
+			if result.err != nil {
+				return evicted, errors.Wrap(result.err, "cannot evict all pods")
 			}
+			evicted = append(evicted, result.pod)
 		case <-deadline:
-			return errors.Wrap(errTimeout{}, "timed out waiting for evictions to complete")
+			return evicted, errors.Wrap(errTimeout{}, "timed out waiting for evictions to complete")
+		// Evictions not yet dispatched when the drain is aborted never send a result
+		case <-ctx.Done():
+			return evicted, errors.Wrap(ctx.Err(), "drain aborted")
 		}
 	}
-	return nil
+
+	if len(workloads) == 0 {
+		return evicted, nil
+	}
+	d.setPhase(PhaseVerifying)
+	return evicted, d.verifyReplacements(ctx, nodeName, workloads)
 }
 
-// evict a pod from a node while respecting the pod's tolerations and grace period
-func (d *APICordonDrainer) evict(p *v1.Pod, abort <-chan struct{}, e chan<- error) {
-	gracePeriod := int64(d.maxGracePeriod.Seconds())
-	zap.S().Infow("Evicting Pod", "pod", p.Name, "namespace", p.Namespace)
+// dispatchEvictions starts the evictions of the pods in order, sending one result per pod to results
+// and adding them to the evictions in progress. It stops once the schedule no longer allows drains
+func (d *APICordonDrainer) dispatchEvictions(ctx context.Context, pods []v1.Pod, results chan<- evictionResult, evictions *sync.WaitGroup) {
+	limit := d.currentSettings().maxConcurrentEvictions
+	if limit <= 0 {
+		limit = len(pods)
+	}
+	slots := make(chan struct{}, limit)
+	// Closed once the previous replica of a controller is evicted and, if enabled, replaced
+	previous := map[string]chan struct{}{}
+
+	for i := range pods {
+		p := &pods[i]
+		key := controllerKey(p)
+		if prev, ok := previous[key]; ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-prev:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case slots <- struct{}{}:
+		}
+		if blocked := d.currentSettings().schedule.Blocked(d.clock.Now()); blocked != "" {
+			results <- evictionResult{pod: p, err: fmt.Errorf("%w, %s, pod %s/%s not evicted", ErrScheduleBlocked, blocked, p.Namespace, p.Name)}
+			return
+		}
+
+		done := make(chan struct{})
+		previous[key] = done
+		evictions.Add(1)
+		go func() {
+			defer evictions.Done()
+			defer close(done)
+			results <- evictionResult{pod: p, err: d.evictAndReplace(ctx, p, func() { <-slots })}
+		}()
+	}
+}
+
+// evictAndReplace evicts a pod and, if enabled, waits for its replacement to be ready.
+// The eviction slot is released as soon as the pod is gone.
+func (d *APICordonDrainer) evictAndReplace(ctx context.Context, p *v1.Pod, release func()) error {
+	settings := d.currentSettings()
+	var w *workload
+	want := 0
+	if settings.waitForReplacements && hasReplacement(p) {
+		var err error
+		if w, err = d.newWorkload(ctx, p); err == nil {
+			want, err = d.readyReplicas(ctx, w)
+		}
+		if err != nil {
+			release()
+			return err
+		}
+	}
+
+	err := d.evict(ctx, p)
+	release()
+	if err != nil || w == nil {
+		return err
+	}
+	return errors.Wrapf(d.awaitReplacement(ctx, w, want, settings.replacementTimeout),
+		"cannot confirm the replacement of pod %s/%s is ready", p.GetNamespace(), p.GetName())
+}
+
+// evict a pod from a node while respecting the pod's tolerations and grace period.
+// Evictions refused by a pod disruption budget are retried with an exponential backoff
+// honouring the server's Retry-After, for at most the PDB wait timeout. If enabled, pods
+// whose eviction is refused past the delete fallback timeout are deleted instead, and evicted
+// pods still there after the delete timeout are deleted again with the shortest grace period.
+func (d *APICordonDrainer) evict(ctx context.Context, p *v1.Pod) (err error) {
+	ctx, span := tracing.Start(ctx, "evict", podAttributes(p)...)
+	defer func() { tracing.End(span, err) }()
+	settings := d.currentSettings()
+	gracePeriod := int64(settings.maxGracePeriod.Seconds())
+	tracing.Logger(ctx).Infow("Evicting Pod", "pod", p.Name, "namespace", p.Namespace)
 	if p.Spec.TerminationGracePeriodSeconds != nil && *p.Spec.TerminationGracePeriodSeconds < gracePeriod {
 		gracePeriod = *p.Spec.TerminationGracePeriodSeconds
 	}
+
+	if settings.evictionVersion.Empty() {
+		if settings.deleteFallbackTimeout <= 0 {
+			return fmt.Errorf("%w: cannot evict pod %s/%s", ErrEvictionUnsupported, p.GetNamespace(), p.GetName())
+		}
+		return d.deletePod(ctx, p, gracePeriod, "eviction API not supported")
+	}
+
+	backoff := evictionBackoff()
+	var blockedSince time.Time
 	for {
-		select {
-		case <-abort:
-			e <- errors.New("pod eviction aborted")
-			return
+		err := d.evictPod(ctx, p, gracePeriod)
+		switch {
+		case ctx.Err() != nil:
+			return errors.Wrap(ctx.Err(), "pod eviction aborted")
+		// The eviction API returns 429 Too Many Requests if a pod
+		// cannot currently be evicted, for example due to a pod
+		// disruption budget.
+		case apierrors.IsTooManyRequests(err):
+			if blockedSince.IsZero() {
+				blockedSince = d.clock.Now()
+			}
+			blocked := d.clock.Since(blockedSince)
+			if settings.deleteFallbackTimeout > 0 && blocked >= settings.deleteFallbackTimeout {
+				return d.deletePod(ctx, p, gracePeriod, fmt.Sprintf("eviction refused for %v, %s", blocked, d.describeBlockingPDBs(ctx, p, err)))
+			}
+			remaining := settings.pdbWaitTimeout - blocked
+			if remaining <= 0 {
+				return fmt.Errorf("%w: pod %s/%s waited %v, %s", ErrEvictionBlocked, p.GetNamespace(), p.GetName(),
+					settings.pdbWaitTimeout, d.describeBlockingPDBs(ctx, p, err))
+			}
+			if settings.deleteFallbackTimeout > 0 && settings.deleteFallbackTimeout-blocked < remaining {
+				remaining = settings.deleteFallbackTimeout - blocked
+			}
+
+			delay := backoff.Step()
+			if seconds, ok := apierrors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > delay {
+				delay = time.Duration(seconds) * time.Second
+			}
+			if delay > remaining {
+				delay = remaining
+			}
+			span.AddEvent("eviction refused", trace.WithAttributes(attribute.String("reason", err.Error())))
+			tracing.Logger(ctx).Infow("Eviction refused, retrying", "pod", p.Name, "namespace", p.Namespace, "retryIn", delay, "reason", err)
+			select {
+			case <-ctx.Done():
+				return errors.Wrap(ctx.Err(), "pod eviction aborted")
+			case <-d.clock.After(delay):
+			}
+		case apierrors.IsNotFound(err):
+			return nil
+		case err != nil:
+			return errors.Wrapf(err, "cannot evict pod %s/%s", p.GetNamespace(), p.GetName())
 		default:
-			err := d.c.CoreV1().Pods(p.Namespace).Evict(context.TODO(),
-				&policy.Eviction{
-					ObjectMeta:    metav1.ObjectMeta{Namespace: p.Namespace, Name: p.Name},
-					DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod},
-				})
-			switch {
-			// The eviction API returns 429 Too Many Requests if a pod
-			// cannot currently be evicted, for example due to a pod
-			// disruption budget.
-			case apierrors.IsTooManyRequests(err):
-				time.Sleep(5 * time.Second)
-			case apierrors.IsNotFound(err):
-				e <- nil
-				return
-			case err != nil:
-				e <- errors.Wrapf(err, "cannot evict pod %s/%s", p.GetNamespace(), p.GetName())
-				return
-			default:
-				e <- errors.Wrapf(d.awaitDeletion(p, d.deleteTimeout()), "cannot confirm pod %s/%s was deleted", p.GetNamespace(), p.GetName())
-				return
+			err := d.awaitDeletion(ctx, p, d.deleteTimeout())
+			if errors.Is(err, wait.ErrWaitTimeout) && settings.deleteFallbackTimeout > 0 {
+				return d.deletePod(ctx, p, 1, fmt.Sprintf("evicted pod still there after %v", d.deleteTimeout()))
 			}
+			return errors.Wrapf(err, "cannot confirm pod %s/%s was deleted", p.GetNamespace(), p.GetName())
 		}
 	}
 }
 
 // awaitDeletion handles grace period for Pod Deletion before sending a signal that it timed out
-func (d *APICordonDrainer) awaitDeletion(p *v1.Pod, timeout time.Duration) error {
-	return wait.PollImmediate(1*time.Second, timeout, func() (bool, error) {
-		got, err := d.c.CoreV1().Pods(p.GetNamespace()).Get(d.getContext(), p.GetName(), metav1.GetOptions{})
+func (d *APICordonDrainer) awaitDeletion(ctx context.Context, p *v1.Pod, timeout time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "await-deletion", podAttributes(p)...)
+	defer func() { tracing.End(span, err) }()
+	deadline := d.clock.After(timeout)
+	for {
+		got, err := d.c.CoreV1().Pods(p.GetNamespace()).Get(ctx, p.GetName(), metav1.GetOptions{})
 		if apierrors.IsNotFound(err) {
-			return true, nil
+			return nil
 		}
 		if err != nil {
-			return false, errors.Wrapf(err, "cannot get pod %s/%s", p.GetNamespace(), p.GetName())
+			return errors.Wrapf(err, "cannot get pod %s/%s", p.GetNamespace(), p.GetName())
 		}
 		if got.GetUID() != p.GetUID() {
-			return true, nil
+			return nil
 		}
-		return false, nil
-	})
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "pod eviction aborted")
+		case <-deadline:
+			if len(got.Finalizers) > 0 {
+				// Nothing but the controllers owning the finalizers can delete the pod
+				return errors.Wrapf(wait.ErrWaitTimeout, "pod is held by the finalizers %s", strings.Join(got.Finalizers, ", "))
+			}
+			return wait.ErrWaitTimeout
+		case <-d.clock.After(DefaultDeletionPoll):
+		}
+	}
+}
+
+// podAttributes identifies the pod of a span
+func podAttributes(p *v1.Pod) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.String("namespace", p.GetNamespace()), attribute.String("pod", p.GetName())}
+}
+
+// SetClock replaces the clock the drainer measures time with
+func (d *APICordonDrainer) SetClock(clock clock.Clock) {
+	d.clock = clock
 }
 
-// SetLastNodeAddition sets the time the last node was added to the cluster
-func (d *APICordonDrainer) SetLastNodeAddition(time time.Time) {
+// SetLastNodeAddition records a node added to the cluster at the given time, it reports whether
+// that is the newest node addition. Earlier additions are ignored
+func (d *APICordonDrainer) SetLastNodeAddition(time time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.LastNodeAddition.Before(time) {
+		return false
+	}
 	d.LastNodeAddition = time
+	return true
+}
+
+// publishNextDrainWindow exports the next time the schedule allows drains, 0 when it never does
+func (d *APICordonDrainer) publishNextDrainWindow(now time.Time) {
+	if d.s == nil {
+		return
+	}
+	next, ok := d.currentSettings().schedule.NextAllowed(now)
+	if !ok {
+		d.s.DrainerMetrics.NextDrainWindow.Set(0)
+		return
+	}
+	d.s.DrainerMetrics.NextDrainWindow.Set(float64(next.Unix()))
+}
+
+// SetLastNodeReady records a node that became ready at the given time, it reports whether that
+// is the latest time a node became ready. Earlier times are ignored
+func (d *APICordonDrainer) SetLastNodeReady(time time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.LastNodeReady.Before(time) {
+		return false
+	}
+	d.LastNodeReady = time
+	return true
+}
+
+// SetLastScaleDown sets the time of the last scale down, like a scale down started then would
+func (d *APICordonDrainer) SetLastScaleDown(time time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.LastScaleDown = time
+}
+
+// RecordPodChurn records a pod created or deleted at the given time, the pod churn cooldown
+// starts when at least the pod churn threshold of pods were created or deleted within the window.
+// Events may be recorded in any order, those older than the window before the newest one are dropped
+func (d *APICordonDrainer) RecordPodChurn(at time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.settings.podChurnThreshold <= 0 {
+		return
+	}
+
+	// podEvents is kept sorted
+	i := sort.Search(len(d.podEvents), func(i int) bool { return d.podEvents[i].After(at) })
+	d.podEvents = append(d.podEvents, time.Time{})
+	copy(d.podEvents[i+1:], d.podEvents[i:])
+	d.podEvents[i] = at
+
+	since := d.podEvents[len(d.podEvents)-1].Add(-d.settings.podChurnWindow)
+	first := sort.Search(len(d.podEvents), func(i int) bool { return !d.podEvents[i].Before(since) })
+	d.podEvents = append(d.podEvents[:0], d.podEvents[first:]...)
+
+	churn, pods := d.LastPodChurn, 0
+	for start, end := 0, 0; end < len(d.podEvents); end++ {
+		for d.podEvents[end].Sub(d.podEvents[start]) > d.settings.podChurnWindow {
+			start++
+		}
+		if end-start+1 >= d.settings.podChurnThreshold && churn.Before(d.podEvents[end]) {
+			churn, pods = d.podEvents[end], end-start+1
+		}
+	}
+	if pods > 0 {
+		zap.S().Infow("Large pod churn, postponing scale downs", "pods", pods, "window", d.settings.podChurnWindow, "time", churn)
+		d.LastPodChurn = churn
+	}
 }
 
 func (d *APICordonDrainer) getPods(nodeName string) ([]v1.Pod, error) {
@@ -353,12 +920,22 @@ func (d *APICordonDrainer) getContext() context.Context {
 	return context.Background()
 }
 
+// currentSettings returns a copy of the settings in effect
+func (d *APICordonDrainer) currentSettings() drainSettings {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.settings
+}
+
 func (d *APICordonDrainer) deleteTimeout() time.Duration {
-	return d.maxGracePeriod + d.evictionHeadroom
+	settings := d.currentSettings()
+	return settings.maxGracePeriod + settings.evictionHeadroom
 }
 
 // UpdateSettings checks every setting in the drainer and sees if it needs to be updated or not and does the necessary action
 func (d *APICordonDrainer) UpdateSettings(cm *v1.ConfigMap) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	data := cm.Data
 
 	// Enabling/Disabling Drainer
@@ -367,9 +944,9 @@ func (d *APICordonDrainer) UpdateSettings(cm *v1.ConfigMap) error {
 		if err != nil {
 			return err
 		}
-		if d.enabled != sEnabled {
-			d.enabled = sEnabled
-			if d.enabled {
+		if d.settings.enabled != sEnabled {
+			d.settings.enabled = sEnabled
+			if d.settings.enabled {
 				zap.S().Info("Enabling drainer")
 			} else {
 				zap.S().Info("Disabling drainer")
@@ -383,10 +960,10 @@ func (d *APICordonDrainer) UpdateSettings(cm *v1.ConfigMap) error {
 		if err != nil {
 			return err
 		}
-		currentDuration := float64(d.timeGap) / float64(time.Minute)
+		currentDuration := float64(d.settings.timeGap) / float64(time.Minute)
 		if int(currentDuration) != sTimeGap {
 			zap.S().Infow("Changing default time gap between each node drain", "from", currentDuration, "to", sTimeGap)
-			d.timeGap = time.Duration(sTimeGap) * time.Minute
+			d.settings.timeGap = time.Duration(sTimeGap) * time.Minute
 		}
 	}
 
@@ -396,10 +973,61 @@ func (d *APICordonDrainer) UpdateSettings(cm *v1.ConfigMap) error {
 		if err != nil {
 			return err
 		}
-		currentDuration := float64(d.minimumTimeSinceLastAddition) / float64(time.Minute)
+		currentDuration := float64(d.settings.minimumTimeSinceLastAddition) / float64(time.Minute)
 		if int(currentDuration) != sTimeSinceLastAddition {
 			zap.S().Infow("Changing default time gap to start a scale down procedure", "from", currentDuration, "to", sTimeSinceLastAddition)
-			d.minimumTimeSinceLastAddition = time.Duration(sTimeSinceLastAddition) * time.Minute
+			d.settings.minimumTimeSinceLastAddition = time.Duration(sTimeSinceLastAddition) * time.Minute
+		}
+	}
+
+	// Set time since a node became ready
+	if value, ok := data["time_since_node_ready"]; ok {
+		sTimeSinceNodeReady, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		currentDuration := float64(d.settings.minimumTimeSinceNodeReady) / float64(time.Minute)
+		if int(currentDuration) != sTimeSinceNodeReady {
+			zap.S().Infow("Changing the time to wait for scale downs after a node became ready", "from", currentDuration, "to", sTimeSinceNodeReady)
+			d.settings.minimumTimeSinceNodeReady = time.Duration(sTimeSinceNodeReady) * time.Minute
+		}
+	}
+
+	// Set Pod Churn Threshold
+	if value, ok := data["pod_churn_threshold"]; ok {
+		sPodChurnThreshold, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if d.settings.podChurnThreshold != sPodChurnThreshold {
+			zap.S().Infow("Changing the number of created or deleted pods considered a large pod churn", "from", d.settings.podChurnThreshold, "to", sPodChurnThreshold)
+			d.settings.podChurnThreshold = sPodChurnThreshold
+		}
+	}
+
+	// Set pod churn window
+	if value, ok := data["pod_churn_window"]; ok {
+		sPodChurnWindow, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		currentDuration := float64(d.settings.podChurnWindow) / float64(time.Minute)
+		if int(currentDuration) != sPodChurnWindow {
+			zap.S().Infow("Changing the window pod churn is measured over", "from", currentDuration, "to", sPodChurnWindow)
+			d.settings.podChurnWindow = time.Duration(sPodChurnWindow) * time.Minute
+		}
+	}
+
+	// Set pod churn cooldown
+	if value, ok := data["pod_churn_cooldown"]; ok {
+		sPodChurnCooldown, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		currentDuration := float64(d.settings.podChurnCooldown) / float64(time.Minute)
+		if int(currentDuration) != sPodChurnCooldown {
+			zap.S().Infow("Changing the time to wait for scale downs after a large pod churn", "from", currentDuration, "to", sPodChurnCooldown)
+			d.settings.podChurnCooldown = time.Duration(sPodChurnCooldown) * time.Minute
 		}
 	}
 
@@ -409,9 +1037,9 @@ func (d *APICordonDrainer) UpdateSettings(cm *v1.ConfigMap) error {
 		if err != nil {
 			return err
 		}
-		if d.excessNodesThreshold != sExcessNodesThreshold {
-			zap.S().Infow("Changing excess nodes threshold", "from", d.excessNodesThreshold, "to", sExcessNodesThreshold)
-			d.excessNodesThreshold = sExcessNodesThreshold
+		if d.settings.excessNodesThreshold != sExcessNodesThreshold {
+			zap.S().Infow("Changing excess nodes threshold", "from", d.settings.excessNodesThreshold, "to", sExcessNodesThreshold)
+			d.settings.excessNodesThreshold = sExcessNodesThreshold
 		}
 	}
 
@@ -421,9 +1049,9 @@ func (d *APICordonDrainer) UpdateSettings(cm *v1.ConfigMap) error {
 		if err != nil {
 			return err
 		}
-		if d.minimumNodes != sMinimumNodes {
-			zap.S().Infow("Changing the value of minimum nodes", "from", d.minimumNodes, "to", sMinimumNodes)
-			d.minimumNodes = sMinimumNodes
+		if d.settings.minimumNodes != sMinimumNodes {
+			zap.S().Infow("Changing the value of minimum nodes", "from", d.settings.minimumNodes, "to", sMinimumNodes)
+			d.settings.minimumNodes = sMinimumNodes
 		}
 	}
 
@@ -433,10 +1061,139 @@ func (d *APICordonDrainer) UpdateSettings(cm *v1.ConfigMap) error {
 		if err != nil {
 			return err
 		}
-		if d.minimumNonTaintedNodes != sMinimumNonTaintedNodes {
-			zap.S().Infow("Changing the value of minimum non tainted nodes", "from", d.minimumNonTaintedNodes, "to", sMinimumNonTaintedNodes)
-			d.minimumNonTaintedNodes = sMinimumNonTaintedNodes
+		if d.settings.minimumNonTaintedNodes != sMinimumNonTaintedNodes {
+			zap.S().Infow("Changing the value of minimum non tainted nodes", "from", d.settings.minimumNonTaintedNodes, "to", sMinimumNonTaintedNodes)
+			d.settings.minimumNonTaintedNodes = sMinimumNonTaintedNodes
+		}
+	}
+
+	// Set Max Concurrent Evictions
+	if value, ok := data["max_concurrent_evictions"]; ok {
+		sMaxConcurrentEvictions, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if d.settings.maxConcurrentEvictions != sMaxConcurrentEvictions {
+			zap.S().Infow("Changing the maximum number of concurrent evictions", "from", d.settings.maxConcurrentEvictions, "to", sMaxConcurrentEvictions)
+			d.settings.maxConcurrentEvictions = sMaxConcurrentEvictions
+		}
+	}
+
+	// Enabling/Disabling waiting for replacement pods
+	if value, ok := data["wait_for_replacements"]; ok {
+		sWaitForReplacements, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		if d.settings.waitForReplacements != sWaitForReplacements {
+			zap.S().Infow("Changing waiting for replacement pods to be ready", "from", d.settings.waitForReplacements, "to", sWaitForReplacements)
+			d.settings.waitForReplacements = sWaitForReplacements
+		}
+	}
+
+	// Set replacement timeout
+	if value, ok := data["replacement_timeout"]; ok {
+		sReplacementTimeout, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		currentDuration := float64(d.settings.replacementTimeout) / float64(time.Minute)
+		if int(currentDuration) != sReplacementTimeout {
+			zap.S().Infow("Changing the time to wait for a replacement pod to be ready", "from", currentDuration, "to", sReplacementTimeout)
+			d.settings.replacementTimeout = time.Duration(sReplacementTimeout) * time.Minute
+		}
+	}
+
+	// Set drain timeout
+	if value, ok := data["drain_timeout"]; ok {
+		sDrainTimeout, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		currentDuration := float64(d.settings.drainTimeout) / float64(time.Minute)
+		if int(currentDuration) != sDrainTimeout {
+			zap.S().Infow("Changing the time a node drain may take", "from", currentDuration, "to", sDrainTimeout)
+			d.settings.drainTimeout = time.Duration(sDrainTimeout) * time.Minute
+		}
+	}
+
+	// Set PDB wait timeout
+	if value, ok := data["pdb_wait_timeout"]; ok {
+		sPDBWaitTimeout, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		currentDuration := float64(d.settings.pdbWaitTimeout) / float64(time.Minute)
+		if int(currentDuration) != sPDBWaitTimeout {
+			zap.S().Infow("Changing the time an eviction may be blocked by a pod disruption budget", "from", currentDuration, "to", sPDBWaitTimeout)
+			d.settings.pdbWaitTimeout = time.Duration(sPDBWaitTimeout) * time.Minute
+		}
+	}
+
+	// Set delete fallback timeout
+	if value, ok := data["delete_fallback_timeout"]; ok {
+		sDeleteFallbackTimeout, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		currentDuration := float64(d.settings.deleteFallbackTimeout) / float64(time.Minute)
+		if int(currentDuration) != sDeleteFallbackTimeout {
+			zap.S().Infow("Changing the time after which pods whose eviction is refused are deleted", "from", currentDuration, "to", sDeleteFallbackTimeout)
+			d.settings.deleteFallbackTimeout = time.Duration(sDeleteFallbackTimeout) * time.Minute
+		}
+	}
+	// Evictions refused past the PDB wait fail the drain, the fallback would never be reached
+	if d.settings.deleteFallbackTimeout > 0 && d.settings.pdbWaitTimeout < d.settings.deleteFallbackTimeout {
+		zap.S().Warnw("Extending the time an eviction may be blocked by a pod disruption budget to the delete fallback timeout",
+			"from", d.settings.pdbWaitTimeout, "to", d.settings.deleteFallbackTimeout)
+		d.settings.pdbWaitTimeout = d.settings.deleteFallbackTimeout
+	}
+
+	// Set replacement verification timeout
+	if value, ok := data["verify_timeout"]; ok {
+		sVerifyTimeout, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		currentDuration := float64(d.settings.verifyTimeout) / float64(time.Minute)
+		if int(currentDuration) != sVerifyTimeout {
+			zap.S().Infow("Changing the time to wait for the replacements of drained pods to be scheduled", "from", currentDuration, "to", sVerifyTimeout)
+			d.settings.verifyTimeout = time.Duration(sVerifyTimeout) * time.Minute
+		}
+	}
+
+	// Enabling/Disabling waiting for the replacements of drained pods to be ready
+	if value, ok := data["verify_ready"]; ok {
+		sVerifyReady, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		if d.settings.verifyReady != sVerifyReady {
+			zap.S().Infow("Changing waiting for the replacements of drained pods to be ready", "from", d.settings.verifyReady, "to", sVerifyReady)
+			d.settings.verifyReady = sVerifyReady
+		}
+	}
+
+	// Set drain schedule
+	sSchedule := d.settings.scheduleSettings
+	for key, value := range map[string]*string{
+		"drain_schedule_timezone": &sSchedule.timezone,
+		"drain_allowed_windows":   &sSchedule.allowedWindows,
+		"drain_blocked_windows":   &sSchedule.blockedWindows,
+		"drain_freeze_periods":    &sSchedule.freezePeriods,
+	} {
+		if v, ok := data[key]; ok {
+			*value = v
+		}
+	}
+	if d.settings.scheduleSettings != sSchedule {
+		s, err := schedule.Parse(sSchedule.timezone, sSchedule.allowedWindows, sSchedule.blockedWindows, sSchedule.freezePeriods)
+		if err != nil {
+			return err
 		}
+		zap.S().Infow("Changing the drain schedule", "from", d.settings.schedule.String(), "to", s.String())
+		d.settings.schedule = s
+		d.settings.scheduleSettings = sSchedule
 	}
 
 	zap.S().Info("Drainer settings update successful")
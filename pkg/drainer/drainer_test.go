@@ -2,13 +2,37 @@ package drainer
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/SAP/node-refiner/pkg/audit"
+	"github.com/SAP/node-refiner/pkg/notifier"
+	internaltypes "github.com/SAP/node-refiner/pkg/types"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
 )
 
 const (
@@ -23,7 +47,7 @@ func TestAddNode(t *testing.T) {
 	client := fake.NewSimpleClientset(node(false))
 	_, err := client.CoreV1().Nodes().Get(context.TODO(), testNodeName, meta_v1.GetOptions{})
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			t.Error(err)
 		} else {
 			t.Errorf("failed to get node: %s", err)
@@ -112,3 +136,1128 @@ func TestCordonUncordon(t *testing.T) {
 		return
 	}
 }
+
+// TestCordonMissingNode tests that cordoning a node that no longer exists is reported as an error instead of crashing
+func TestCordonMissingNode(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	d := NewAPICordonDrainer(client, nil)
+
+	err := d.Cordon(testNodeName)
+	if !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func readyCluster() *internaltypes.ClusterManifest {
+	return &internaltypes.ClusterManifest{ExcessNodes: 3, NumberOfNodes: 5, NumberOfNonTaintedNodes: 5}
+}
+
+// notifyingClock reports every duration waited on so tests can step the clock precisely
+type notifyingClock struct {
+	*clocktesting.FakeClock
+	waits chan time.Duration
+}
+
+func newNotifyingClock() *notifyingClock {
+	return &notifyingClock{FakeClock: clocktesting.NewFakeClock(time.Now()), waits: make(chan time.Duration, 10)}
+}
+
+func (c *notifyingClock) After(d time.Duration) <-chan time.Time {
+	ch := c.FakeClock.After(d)
+	c.waits <- d
+	return ch
+}
+
+// drain drains the test node, stepping the clock whenever the drainer waits for at most maxStep
+// up to the drain timeout, and returns how long the drain took on the clock
+func (c *notifyingClock) drain(t *testing.T, d *APICordonDrainer, maxStep time.Duration) (time.Duration, error) {
+	return c.run(t, d.settings.drainTimeout, maxStep, func() error { return d.Drain(testNodeName) })
+}
+
+// run calls f, stepping the clock whenever it waits for at most maxStep up to the limit,
+// and returns how long f took on the clock
+func (c *notifyingClock) run(t *testing.T, limit, maxStep time.Duration, f func() error) (time.Duration, error) {
+	for len(c.waits) > 0 {
+		<-c.waits
+	}
+	deadline := c.Now().Add(limit)
+	done := make(chan struct{})
+	var elapsed time.Duration
+	var err error
+	go func() {
+		defer close(done)
+		start := c.Now()
+		err = f()
+		elapsed = c.Since(start)
+	}()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case <-done:
+			return elapsed, err
+		case wait := <-c.waits:
+			if wait <= maxStep && c.Now().Before(deadline) {
+				c.Step(wait)
+			}
+		case <-timeout:
+			t.Fatalf("gave up waiting at %v", c.Now())
+		}
+	}
+}
+
+// TestEvaluateDrainCooldowns tests that drains wait for the node addition and scale down cooldowns
+func TestEvaluateDrainCooldowns(t *testing.T) {
+	fc := clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+	d := NewAPICordonDrainer(nil, nil)
+	d.SetClock(fc)
+	d.SetLastNodeAddition(fc.Now())
+
+	if d.EvaluateDrain(readyCluster()).Allowed {
+		t.Fatalf("Drain allowed right after a node was added")
+	}
+	fc.Step(DefaultMinimumTimeSinceLastAddition - time.Second)
+	if d.EvaluateDrain(readyCluster()).Allowed {
+		t.Fatalf("Drain allowed before the node addition cooldown passed")
+	}
+	fc.Step(time.Second)
+	if decision := d.EvaluateDrain(readyCluster()); !decision.Allowed {
+		t.Fatalf("Drain not allowed after the node addition cooldown: %s", decision.Reason)
+	}
+
+	d.SetLastScaleDown(fc.Now())
+	fc.Step(DefaultTimeGap / 2)
+	if d.EvaluateDrain(readyCluster()).Allowed {
+		t.Fatalf("Drain allowed before the time gap since the last scale down passed")
+	}
+	fc.Step(DefaultTimeGap / 2)
+	if decision := d.EvaluateDrain(readyCluster()); !decision.Allowed {
+		t.Fatalf("Drain not allowed after the time gap: %s", decision.Reason)
+	}
+}
+
+// TestEvaluateDrainReadinessAndChurn tests that scale downs wait for not ready nodes, nodes
+// that recently became ready and large pod churn
+func TestEvaluateDrainReadinessAndChurn(t *testing.T) {
+	start := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)
+	fc := clocktesting.NewFakeClock(start)
+	d := NewAPICordonDrainer(nil, nil)
+	d.SetClock(fc)
+
+	cluster := readyCluster()
+	cluster.NumberOfNotReadyNodes = 1
+	if d.EvaluateDrain(cluster).Allowed {
+		t.Fatalf("Drain allowed while a node is not ready")
+	}
+
+	d.SetLastNodeReady(start)
+	if d.EvaluateDrain(readyCluster()).Allowed {
+		t.Fatalf("Drain allowed right after a node became ready")
+	}
+	fc.Step(DefaultMinimumTimeSinceNodeReady)
+	if decision := d.EvaluateDrain(readyCluster()); !decision.Allowed {
+		t.Fatalf("Drain not allowed after the node ready cooldown: %s", decision.Reason)
+	}
+
+	// Pods spread over more than the window are no churn
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"pod_churn_threshold": "3", "pod_churn_window": "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		d.RecordPodChurn(fc.Now().Add(time.Duration(-i) * time.Minute))
+	}
+	if decision := d.EvaluateDrain(readyCluster()); !decision.Allowed {
+		t.Fatalf("Drain not allowed after pods spread over several minutes: %s", decision.Reason)
+	}
+
+	// Events are counted within the window whatever order they are recorded in
+	d.RecordPodChurn(fc.Now().Add(-10 * time.Second))
+	if d.EvaluateDrain(readyCluster()).Allowed {
+		t.Fatalf("Drain allowed right after a pod churn")
+	}
+	if !d.LastPodChurn.Equal(fc.Now()) {
+		t.Errorf("Expected the pod churn at %v, got %v", fc.Now(), d.LastPodChurn)
+	}
+	fc.Step(DefaultPodChurnCooldown)
+	if decision := d.EvaluateDrain(readyCluster()); !decision.Allowed {
+		t.Fatalf("Drain not allowed after the pod churn cooldown: %s", decision.Reason)
+	}
+}
+
+// TestEvaluateDrainSchedule tests that drains only happen in the allowed windows of the schedule
+func TestEvaluateDrainSchedule(t *testing.T) {
+	monday := time.Date(2021, 5, 3, 12, 0, 0, 0, time.UTC)
+	d := NewAPICordonDrainer(nil, nil)
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{
+		"drain_schedule_timezone": "Europe/Berlin",
+		"drain_allowed_windows":   "0 22 * * mon-fri 8h",
+		"drain_freeze_periods":    "2021-05-04/2021-05-04",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	decision := d.EvaluateDrainAt(monday, readyCluster())
+	if decision.Allowed {
+		t.Fatalf("Drain allowed outside of the allowed windows")
+	}
+	if !strings.Contains(decision.Reason, "next allowed at 2021-05-03T22:00:00+02:00") {
+		t.Errorf("Expected the next window in the reason, got %s", decision.Reason)
+	}
+	if decision := d.EvaluateDrainAt(monday.Add(9*time.Hour), readyCluster()); !decision.Allowed {
+		t.Errorf("Drain not allowed within the window: %s", decision.Reason)
+	}
+	if d.EvaluateDrainAt(monday.Add(32*time.Hour), readyCluster()).Allowed {
+		t.Errorf("Drain allowed during the freeze")
+	}
+
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"drain_allowed_windows": "0 22 * * 8 8h"}}); err == nil {
+		t.Errorf("Expected an invalid window to be refused")
+	}
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"drain_allowed_windows": "", "drain_freeze_periods": ""}}); err != nil {
+		t.Fatal(err)
+	}
+	if decision := d.EvaluateDrainAt(monday, readyCluster()); !decision.Allowed {
+		t.Errorf("Drain not allowed once the schedule is cleared: %s", decision.Reason)
+	}
+}
+
+func testPod(name string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "default", UID: k8stypes.UID(name)},
+		Spec: v1.PodSpec{NodeName: testNodeName}}
+}
+
+// TestDrainTimeout tests that a drain gives up when evicted pods are never deleted
+func TestDrainTimeout(t *testing.T) {
+	client := fake.NewSimpleClientset(node(true), testPod("stuck"))
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return action.GetSubresource() == "eviction", nil, nil
+	})
+	fc := newNotifyingClock()
+	d := NewAPICordonDrainer(client, nil)
+	d.SetClock(fc)
+
+	elapsed, err := fc.drain(t, d, DefaultDeletionPoll)
+	if !errors.Is(err, wait.ErrWaitTimeout) {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	if elapsed != d.deleteTimeout() {
+		t.Errorf("Drain timed out after %v, expected the delete timeout of %v", elapsed, d.deleteTimeout())
+	}
+
+	// The drain as a whole gives up first when the drain timeout is shorter
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"drain_timeout": "2"}}); err != nil {
+		t.Fatal(err)
+	}
+	elapsed, err = fc.drain(t, d, DefaultDeletionPoll)
+	if !errors.Is(err, errTimeout{}) {
+		t.Fatalf("Expected the drain to time out, got %v", err)
+	}
+	if elapsed != 2*time.Minute {
+		t.Errorf("Drain timed out after %v, expected the drain timeout of %v", elapsed, 2*time.Minute)
+	}
+
+	// With the delete fallback the evicted pod is deleted once the delete timeout passed
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"drain_timeout": "30", "delete_fallback_timeout": "5"}}); err != nil {
+		t.Fatal(err)
+	}
+	elapsed, err = fc.drain(t, d, DefaultDeletionPoll)
+	if err != nil {
+		t.Fatalf("Unexpected error while draining: %v", err)
+	}
+	if elapsed != d.deleteTimeout() {
+		t.Errorf("Pod was deleted after %v, expected the delete timeout of %v", elapsed, d.deleteTimeout())
+	}
+	if _, err := client.CoreV1().Pods("default").Get(context.TODO(), "stuck", meta_v1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the pod to be deleted, got %v", err)
+	}
+}
+
+// blockingReactor refuses the eviction of the pod the given number of times, then deletes it
+func blockingReactor(client *fake.Clientset, refusals int, retryAfterSeconds int) (attempts *int) {
+	attempts = new(int)
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		*attempts++
+		if refusals < 0 || *attempts <= refusals {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", retryAfterSeconds)
+		}
+		name := action.(k8stesting.CreateAction).GetObject().(meta_v1.Object).GetName()
+		return true, nil, client.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), "default", name)
+	})
+	return attempts
+}
+
+// TestDrainRetriesTooManyRequests tests that evictions blocked by a disruption budget are retried with an exponential backoff
+func TestDrainRetriesTooManyRequests(t *testing.T) {
+	client := fake.NewSimpleClientset(node(true), testPod("guarded"))
+	attempts := blockingReactor(client, 3, 0)
+	fc := newNotifyingClock()
+	d := NewAPICordonDrainer(client, nil)
+	d.SetClock(fc)
+
+	elapsed, err := fc.drain(t, d, 2*DefaultEvictionRetryCap)
+	if err != nil {
+		t.Fatalf("Unexpected error while draining: %v", err)
+	}
+	if *attempts != 4 {
+		t.Errorf("Expected 4 eviction attempts, got %d", *attempts)
+	}
+	// 5s, 10s and 20s with up to 20% jitter each
+	if min, max := 7*DefaultEvictionRetry, 7*DefaultEvictionRetry*12/10; elapsed < min || elapsed >= max {
+		t.Errorf("Evictions were retried after %v, expected between %v and %v", elapsed, min, max)
+	}
+}
+
+// TestDrainHonoursRetryAfter tests that the server's Retry-After takes precedence over a shorter backoff
+func TestDrainHonoursRetryAfter(t *testing.T) {
+	client := fake.NewSimpleClientset(node(true), testPod("guarded"))
+	blockingReactor(client, 2, 30)
+	fc := newNotifyingClock()
+	d := NewAPICordonDrainer(client, nil)
+	d.SetClock(fc)
+
+	elapsed, err := fc.drain(t, d, 2*DefaultEvictionRetryCap)
+	if err != nil {
+		t.Fatalf("Unexpected error while draining: %v", err)
+	}
+	if elapsed != time.Minute {
+		t.Errorf("Evictions were retried after %v, expected %v", elapsed, time.Minute)
+	}
+}
+
+// TestDrainPDBWaitTimeout tests that a drain gives up on evictions blocked for longer than the PDB wait timeout
+// and reports the blocking disruption budget
+func TestDrainPDBWaitTimeout(t *testing.T) {
+	guarded := testPod("guarded")
+	guarded.Labels = map[string]string{"app": "web"}
+	pdb := &policy.PodDisruptionBudget{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       policy.PodDisruptionBudgetSpec{Selector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		Status:     policy.PodDisruptionBudgetStatus{CurrentHealthy: 2, DesiredHealthy: 2},
+	}
+	client := fake.NewSimpleClientset(node(true), guarded, pdb)
+	blockingReactor(client, -1, 0)
+	fc := newNotifyingClock()
+	d := NewAPICordonDrainer(client, nil)
+	d.SetClock(fc)
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"pdb_wait_timeout": "3"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	elapsed, err := fc.drain(t, d, 2*DefaultEvictionRetryCap)
+	if !errors.Is(err, ErrEvictionBlocked) {
+		t.Fatalf("Expected the eviction to be blocked, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "default/web") {
+		t.Errorf("Expected the error to name the disruption budget, got %v", err)
+	}
+	if elapsed != 3*time.Minute {
+		t.Errorf("Eviction gave up after %v, expected %v", elapsed, 3*time.Minute)
+	}
+}
+
+func ownedPod(name, owner string, priority int32) v1.Pod {
+	p := testPod(name)
+	p.Spec.Priority = &priority
+	if owner != "" {
+		controller := true
+		p.OwnerReferences = []meta_v1.OwnerReference{{Kind: "ReplicaSet", Name: owner, UID: k8stypes.UID(owner), Controller: &controller}}
+	}
+	return *p
+}
+
+// TestEvictionOrder tests that low priority pods go first and replicas of a controller are spread out
+func TestEvictionOrder(t *testing.T) {
+	pods := []v1.Pod{
+		ownedPod("api-1", "api", 1000),
+		ownedPod("web-2", "web", 0),
+		ownedPod("web-1", "web", 0),
+		ownedPod("api-2", "api", 1000),
+		ownedPod("batch-1", "batch", 0),
+		ownedPod("standalone", "", 0),
+		ownedPod("web-3", "web", 0),
+		ownedPod("batch-2", "batch", 0),
+		ownedPod("cheap-1", "cheap", -10),
+	}
+
+	var got []string
+	for _, p := range evictionOrder(pods) {
+		got = append(got, p.Name)
+	}
+	want := []string{"cheap-1", "batch-1", "standalone", "web-1", "batch-2", "web-2", "web-3", "api-1", "api-2"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected eviction order %v, got %v", want, got)
+	}
+}
+
+// TestDrainConcurrencyLimit tests that no more than the configured number of evictions run at once
+func TestDrainConcurrencyLimit(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f"}
+	objs := []runtime.Object{node(true)}
+	for _, name := range names {
+		objs = append(objs, testPod(name))
+	}
+	client := fake.NewSimpleClientset(objs...)
+
+	// Evicted pods stay around until the test deletes them
+	var lock sync.Mutex
+	evicted := map[string]bool{}
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		evicted[action.(k8stesting.CreateAction).GetObject().(meta_v1.Object).GetName()] = true
+		return true, nil, nil
+	})
+	fc := clocktesting.NewFakeClock(time.Now())
+	d := NewAPICordonDrainer(client, nil)
+	d.SetClock(fc)
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"max_concurrent_evictions": "2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- d.Drain(testNodeName) }()
+	deleted := map[string]bool{}
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Unexpected error while draining: %v", err)
+			}
+			if len(deleted) != len(names) {
+				t.Errorf("Expected %d evictions, got %d", len(names), len(deleted))
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+			lock.Lock()
+			if inFlight := len(evicted) - len(deleted); inFlight > 2 {
+				t.Fatalf("Expected at most 2 evictions in flight, got %d", inFlight)
+			}
+			for name := range evicted {
+				if !deleted[name] {
+					deleted[name] = true
+					if err := client.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), "default", name); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			lock.Unlock()
+			fc.Step(DefaultDeletionPoll)
+		}
+	}
+}
+
+// TestDiscoverEvictionAPI tests that the eviction version reported by the server is used
+func TestDiscoverEvictionAPI(t *testing.T) {
+	policyGroup := func(version string) *meta_v1.APIResourceList {
+		return &meta_v1.APIResourceList{GroupVersion: "policy/" + version}
+	}
+	eviction := meta_v1.APIResource{Name: "pods/eviction", Kind: "Eviction"}
+	evictionV1 := meta_v1.APIResource{Name: "pods/eviction", Kind: "Eviction", Group: "policy", Version: "v1"}
+
+	for name, tc := range map[string]struct {
+		resources []*meta_v1.APIResourceList
+		want      string
+	}{
+		"reported version": {
+			resources: []*meta_v1.APIResourceList{{GroupVersion: "v1", APIResources: []meta_v1.APIResource{evictionV1}}, policyGroup("v1beta1")},
+			want:      "policy/v1",
+		},
+		"preferred policy version": {
+			resources: []*meta_v1.APIResourceList{{GroupVersion: "v1", APIResources: []meta_v1.APIResource{eviction}}, policyGroup("v1beta1")},
+			want:      "policy/v1beta1",
+		},
+		"no eviction subresource": {
+			resources: []*meta_v1.APIResourceList{{GroupVersion: "v1"}, policyGroup("v1")},
+		},
+		"no policy group": {
+			resources: []*meta_v1.APIResourceList{{GroupVersion: "v1", APIResources: []meta_v1.APIResource{evictionV1}}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			client.Resources = tc.resources
+			d := NewAPICordonDrainer(client, nil)
+			if err := d.DiscoverEvictionAPI(); err != nil {
+				t.Fatal(err)
+			}
+			if got := d.settings.evictionVersion; !(got.Empty() && tc.want == "") && got.String() != tc.want {
+				t.Errorf("Expected eviction version %q, got %q", tc.want, got.String())
+			}
+		})
+	}
+}
+
+// TestEvictPolicyV1 tests that pods are evicted with policy/v1 evictions once the server serves them
+func TestEvictPolicyV1(t *testing.T) {
+	refuse := false
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		var eviction meta_v1.TypeMeta
+		if err := json.NewDecoder(req.Body).Decode(&eviction); err != nil {
+			t.Error(err)
+		}
+		if req.Method != http.MethodPost || req.URL.Path != "/api/v1/namespaces/default/pods/web/eviction" ||
+			eviction.APIVersion != "policy/v1" || eviction.Kind != "Eviction" {
+			t.Errorf("Unexpected eviction %s %s of %+v", req.Method, req.URL.Path, eviction)
+		}
+		res.Header().Set("Content-Type", "application/json")
+		if refuse {
+			res.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(res, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"TooManyRequests","code":429}`)
+			return
+		}
+		res.WriteHeader(http.StatusCreated)
+		fmt.Fprint(res, `{"kind":"Status","apiVersion":"v1","status":"Success","code":201}`)
+	}))
+	defer server.Close()
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewAPICordonDrainer(client, nil)
+	d.settings.evictionVersion = EvictionV1
+
+	pod := &v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"}}
+	if err := d.evictPod(context.TODO(), pod, 30); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	refuse = true
+	if err := d.evictPod(context.TODO(), pod, 30); !apierrors.IsTooManyRequests(err) {
+		t.Errorf("Expected the eviction to be refused, got %v", err)
+	}
+}
+
+// TestDrainDeleteFallback tests that pods whose eviction is refused past the delete fallback timeout are deleted
+func TestDrainDeleteFallback(t *testing.T) {
+	client := fake.NewSimpleClientset(node(true), testPod("guarded"))
+	blockingReactor(client, -1, 0)
+	fc := newNotifyingClock()
+	d := NewAPICordonDrainer(client, nil)
+	d.SetClock(fc)
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"delete_fallback_timeout": "2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	elapsed, err := fc.drain(t, d, 2*DefaultEvictionRetryCap)
+	if err != nil {
+		t.Fatalf("Unexpected error while draining: %v", err)
+	}
+	if elapsed != 2*time.Minute {
+		t.Errorf("Pod was deleted after %v, expected %v", elapsed, 2*time.Minute)
+	}
+	if _, err := client.CoreV1().Pods("default").Get(context.TODO(), "guarded", meta_v1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the pod to be deleted, got %v", err)
+	}
+
+	// A fallback beyond the PDB wait extends the wait instead of never firing
+	client = fake.NewSimpleClientset(node(true), testPod("guarded"))
+	blockingReactor(client, -1, 0)
+	d = NewAPICordonDrainer(client, nil)
+	d.SetClock(fc)
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"pdb_wait_timeout": "10", "delete_fallback_timeout": "15"}}); err != nil {
+		t.Fatal(err)
+	}
+	elapsed, err = fc.drain(t, d, 2*DefaultEvictionRetryCap)
+	if err != nil {
+		t.Fatalf("Unexpected error while draining: %v", err)
+	}
+	if elapsed != 15*time.Minute {
+		t.Errorf("Pod was deleted after %v, expected the delete fallback timeout of %v", elapsed, 15*time.Minute)
+	}
+
+	// Without an eviction API pods are only deleted when the fallback is enabled
+	client = fake.NewSimpleClientset(node(true), testPod("unevictable"))
+	d = NewAPICordonDrainer(client, nil)
+	if err := d.DiscoverEvictionAPI(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Drain(testNodeName); !errors.Is(err, ErrEvictionUnsupported) {
+		t.Errorf("Expected the eviction API to be unsupported, got %v", err)
+	}
+}
+
+// TestScaleDownVerifiesReplacements tests that a scale down is reverted when the replacements of the drained pods stay pending
+func TestScaleDownVerifiesReplacements(t *testing.T) {
+	for name, replacementNode := range map[string]string{"scheduled": "other node", "pending": ""} {
+		t.Run(name, func(t *testing.T) {
+			web := ownedPod("web-1", "web", 0)
+			client := fake.NewSimpleClientset(node(false), &web)
+			// The ReplicaSet replaces the evicted pod
+			client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				replacement := ownedPod("web-2", "web", 0)
+				replacement.Spec.NodeName = replacementNode
+				if err := client.Tracker().Add(&replacement); err != nil {
+					return true, nil, err
+				}
+				return true, nil, client.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), "default", "web-1")
+			})
+			fc := newNotifyingClock()
+			d := NewAPICordonDrainer(client, nil)
+			d.SetClock(fc)
+			if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"verify_timeout": "1"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _ = fc.run(t, time.Hour, DefaultVerifyPoll, func() error {
+				d.ScaleDown(testNodeName)
+				return nil
+			})
+
+			n, err := client.CoreV1().Nodes().Get(context.TODO(), testNodeName, meta_v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			failed := replacementNode == ""
+			if n.Spec.Unschedulable == failed {
+				t.Errorf("Expected the node to be unschedulable: %v", !failed)
+			}
+			if failed != errors.Is(d.LastFailedScaleDownErr, ErrReplacementsPending) {
+				t.Errorf("Unexpected scale down failure: %v", d.LastFailedScaleDownErr)
+			}
+		})
+	}
+}
+
+// TestScaleDownVerifiesOnlyReplacedPods tests that the verification doesn't wait for the pods
+// their controller won't replace: completed pods, Job pods and pods of scaled down controllers
+func TestScaleDownVerifiesOnlyReplacedPods(t *testing.T) {
+	controller := true
+	jobPod := testPod("report-1")
+	jobPod.OwnerReferences = []meta_v1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "report", UID: "report", Controller: &controller}}
+	jobPod.Status.Phase = v1.PodSucceeded
+	failed := ownedPod("api-1", "api", 0)
+	failed.Status.Phase = v1.PodFailed
+
+	replicas := int32(2)
+	rs := &appsv1.ReplicaSet{ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default", UID: "web"},
+		Spec: appsv1.ReplicaSetSpec{Replicas: &replicas, Selector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}}
+	webPod := func(name, nodeName string) *v1.Pod {
+		p := testPod(name)
+		p.Spec.NodeName = nodeName
+		p.Labels = map[string]string{"app": "web"}
+		p.OwnerReferences = []meta_v1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web", UID: "web", Controller: &controller}}
+		return p
+	}
+
+	for name, objects := range map[string][]runtime.Object{
+		"completed job": {jobPod},
+		"failed pod":    {&failed},
+		"scaled down":   {rs, webPod("web-1", testNodeName), webPod("web-2", "other node")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(append([]runtime.Object{node(false)}, objects...)...)
+			// The evicted pods are gone and the ReplicaSet is scaled down meanwhile
+			client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				if _, err := client.Tracker().Get(appsv1.SchemeGroupVersion.WithResource("replicasets"), "default", "web"); err == nil {
+					scaled := rs.DeepCopy()
+					*scaled.Spec.Replicas = 1
+					if err := client.Tracker().Update(appsv1.SchemeGroupVersion.WithResource("replicasets"), scaled, "default"); err != nil {
+						return true, nil, err
+					}
+				}
+				name := action.(k8stesting.CreateAction).GetObject().(meta_v1.Object).GetName()
+				// The fake clientset lists the pods of every node, the one on the other node is replaced
+				if name == "web-2" {
+					if err := client.Tracker().Add(webPod("web-3", "other node")); err != nil {
+						return true, nil, err
+					}
+				}
+				return true, nil, client.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), "default", name)
+			})
+			fc := newNotifyingClock()
+			d := NewAPICordonDrainer(client, nil)
+			d.SetClock(fc)
+			if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"verify_timeout": "1"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _ = fc.run(t, time.Hour, DefaultVerifyPoll, func() error {
+				d.ScaleDown(testNodeName)
+				return nil
+			})
+
+			if d.LastFailedScaleDownErr != nil {
+				t.Errorf("Unexpected scale down failure: %v", d.LastFailedScaleDownErr)
+			}
+		})
+	}
+}
+
+// TestDrainWaitsForReplacements tests that with wait_for_replacements an eviction only completes
+// once the ReplicaSet is back to its ready replicas, listing only the pods of the ReplicaSet
+func TestDrainWaitsForReplacements(t *testing.T) {
+	for name, readyAfter := range map[string]int{"ready": 2, "never ready": -1} {
+		t.Run(name, func(t *testing.T) {
+			controller := true
+			webPod := func(name string) *v1.Pod {
+				p := testPod(name)
+				p.Labels = map[string]string{"app": "web"}
+				p.OwnerReferences = []meta_v1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web", UID: "web", Controller: &controller}}
+				p.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+				return p
+			}
+			rs := &appsv1.ReplicaSet{ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default", UID: "web"},
+				Spec: appsv1.ReplicaSetSpec{Selector: &meta_v1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}}
+			other := testPod("other")
+			other.Spec.NodeName = "other node"
+			client := fake.NewSimpleClientset(node(true), rs, webPod("web-1"), other)
+
+			// The ReplicaSet replaces the evicted pod with one becoming ready after some polls
+			var mu sync.Mutex
+			var selectors []string
+			evicted, polls := false, 0
+			client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				selector := action.(k8stesting.ListAction).GetListRestrictions().Labels.String()
+				if selector != "" {
+					selectors = append(selectors, selector)
+				}
+				if !evicted {
+					return false, nil, nil
+				}
+				if polls++; polls == readyAfter {
+					replacement := webPod("web-2")
+					replacement.Spec.NodeName = "other node"
+					return false, nil, client.Tracker().Update(v1.SchemeGroupVersion.WithResource("pods"), replacement, "default")
+				}
+				return false, nil, nil
+			})
+			client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				name := action.(k8stesting.CreateAction).GetObject().(meta_v1.Object).GetName()
+				if name == "web-1" {
+					mu.Lock()
+					evicted = true
+					mu.Unlock()
+					replacement := webPod("web-2")
+					replacement.Spec.NodeName = "other node"
+					replacement.Status.Conditions = nil
+					if err := client.Tracker().Add(replacement); err != nil {
+						return true, nil, err
+					}
+				}
+				return true, nil, client.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), "default", name)
+			})
+			fc := newNotifyingClock()
+			d := NewAPICordonDrainer(client, nil)
+			d.SetClock(fc)
+			if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"wait_for_replacements": "true", "replacement_timeout": "1"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			elapsed, err := fc.drain(t, d, DefaultDeletionPoll)
+			if readyAfter < 0 {
+				if !errors.Is(err, wait.ErrWaitTimeout) {
+					t.Fatalf("Expected the replacement to time out, got %v", err)
+				}
+				if elapsed != time.Minute {
+					t.Errorf("Gave up after %v, expected the replacement timeout of %v", elapsed, time.Minute)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error while draining: %v", err)
+				}
+				if elapsed != DefaultDeletionPoll {
+					t.Errorf("Drained after %v, expected to wait one poll of %v for the replacement", elapsed, DefaultDeletionPoll)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(selectors) == 0 {
+				t.Fatal("Expected the replicas to be listed")
+			}
+			for _, selector := range selectors {
+				if selector != "app=web" {
+					t.Errorf("Expected the pods to be listed with the selector of the ReplicaSet, got %q", selector)
+				}
+			}
+		})
+	}
+}
+
+// TestDrainStopsWhenScheduleBlocks tests that no pod is evicted any more once a freeze period starts during a drain
+func TestDrainStopsWhenScheduleBlocks(t *testing.T) {
+	client := fake.NewSimpleClientset(node(true), testPod("a"), testPod("b"))
+	d := NewAPICordonDrainer(client, nil)
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"max_concurrent_evictions": "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	// The freeze period starts while the first pod is evicted
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"drain_freeze_periods": "2000-01-01/2100-01-01"}}); err != nil {
+			return true, nil, err
+		}
+		name := action.(k8stesting.CreateAction).GetObject().(meta_v1.Object).GetName()
+		return true, nil, client.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), "default", name)
+	})
+
+	if err := d.Drain(testNodeName); !errors.Is(err, ErrScheduleBlocked) {
+		t.Fatalf("Expected the drain to stop on the freeze, got %v", err)
+	}
+	if _, err := client.CoreV1().Pods("default").Get(context.TODO(), "b", meta_v1.GetOptions{}); err != nil {
+		t.Errorf("Expected the second pod not to be evicted, got %v", err)
+	}
+}
+
+// TestRelievePressure tests that only the scale down in progress is aborted and its node uncordoned,
+// the nodes already drained are left cordoned
+func TestRelievePressure(t *testing.T) {
+	drained := node(false)
+	drained.Name = "drained"
+	client := fake.NewSimpleClientset(drained, node(false))
+	blockingReactor(client, -1, 0)
+	d := NewAPICordonDrainer(client, nil)
+
+	cluster := readyCluster()
+	cluster.NumberOfPendingPods = 1
+	if d.EvaluateDrain(cluster).Allowed {
+		t.Errorf("Drain allowed while pods are pending")
+	}
+
+	d.ScaleDown("drained")
+	if cordoned := d.CordonedNodes(); len(cordoned) != 0 {
+		t.Fatalf("Expected the drained node to be forgotten, got %v", cordoned)
+	}
+	if uncordoned, err := d.RelievePressure(); err != nil || uncordoned != "" {
+		t.Fatalf("Expected no scale down to abort, got %q, %v", uncordoned, err)
+	}
+
+	if _, err := client.CoreV1().Pods("default").Create(context.TODO(), testPod("guarded"), meta_v1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		d.ScaleDown(testNodeName)
+		close(done)
+	}()
+	for start := time.Now(); len(d.Status(time.Now()).Draining) == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("The drain didn't start")
+		}
+	}
+	uncordoned, err := d.RelievePressure()
+	if err != nil || uncordoned != testNodeName {
+		t.Fatalf("Expected to abort the scale down in progress, got %q, %v", uncordoned, err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("The drain wasn't aborted")
+	}
+
+	for name, unschedulable := range map[string]bool{"drained": true, testNodeName: false} {
+		n, err := client.CoreV1().Nodes().Get(context.TODO(), name, meta_v1.GetOptions{})
+		if err != nil || n.Spec.Unschedulable != unschedulable {
+			t.Errorf("Expected %s to be unschedulable: %v, got %v", name, unschedulable, err)
+		}
+	}
+}
+
+// TestPauseAndAbort tests that a paused drainer starts no scale down and that aborting a drain uncordons its node
+func TestPauseAndAbort(t *testing.T) {
+	client := fake.NewSimpleClientset(node(true), testPod("guarded"))
+	blockingReactor(client, -1, 0)
+	d := NewAPICordonDrainer(client, nil)
+	d.SetClock(clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)))
+
+	d.Pause()
+	if decision := d.EvaluateDrain(readyCluster()); decision.Allowed || d.Phase() != PhasePaused {
+		t.Errorf("Expected a paused drainer to refuse drains, got %+v in phase %s", decision, d.Phase())
+	}
+	d.Resume()
+	if decision := d.EvaluateDrain(readyCluster()); !decision.Allowed {
+		t.Errorf("Expected a resumed drainer to allow drains, got %s", decision.Reason)
+	}
+
+	if _, err := d.Abort(); !errors.Is(err, ErrNoDrainInProgress) {
+		t.Errorf("Expected no drain to abort, got %v", err)
+	}
+
+	drained := make(chan error)
+	go func() { drained <- d.Drain(testNodeName) }()
+	for start := time.Now(); len(d.Status(time.Now()).Draining) == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("The drain didn't start")
+		}
+	}
+
+	aborted, err := d.Abort()
+	if err != nil || len(aborted) != 1 || aborted[0] != testNodeName {
+		t.Fatalf("Expected to abort the drain of the test node, got %v, %v", aborted, err)
+	}
+	select {
+	case err := <-drained:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the drain to be cancelled, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("The drain wasn't aborted")
+	}
+	n, err := client.CoreV1().Nodes().Get(context.TODO(), testNodeName, meta_v1.GetOptions{})
+	if err != nil || n.Spec.Unschedulable {
+		t.Errorf("Expected the aborted node to be schedulable again, got %v", err)
+	}
+}
+
+// TestAttemptDrainInProgress tests that no scale down is started while another one is in progress
+func TestAttemptDrainInProgress(t *testing.T) {
+	client := fake.NewSimpleClientset(node(false), testPod("guarded"))
+	blockingReactor(client, -1, 0)
+	d := NewAPICordonDrainer(client, nil)
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"time_gap": "0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if decision := d.AttemptDrain(testNodeName, readyCluster()); !decision.Allowed {
+		t.Fatalf("Expected the first scale down to start, got %s", decision.Reason)
+	}
+	if decision := d.AttemptDrain(testNodeName, readyCluster()); decision.Allowed || !strings.Contains(decision.Reason, ErrScaleDownInProgress.Error()) {
+		t.Errorf("Expected the second scale down to be refused, got %+v", decision)
+	}
+
+	for start := time.Now(); len(d.Status(time.Now()).Draining) == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("The drain didn't start")
+		}
+	}
+	if _, err := d.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); d.Phase() != PhaseIdle; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("The scale down didn't finish, the drainer is %s", d.Phase())
+		}
+	}
+}
+
+type recordingNotifier struct {
+	events []notifier.Event
+}
+
+func (r *recordingNotifier) Notify(event notifier.Event) {
+	r.events = append(r.events, event)
+}
+
+// TestScaleDownNotifications tests that the notifier is told when scale downs start, succeed and fail
+func TestScaleDownNotifications(t *testing.T) {
+	n := node(false)
+	n.Labels["worker.gardener.cloud/pool"] = "workers"
+	fc := clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+	d := NewAPICordonDrainer(fake.NewSimpleClientset(n), nil)
+	d.SetClock(fc)
+	recorder := &recordingNotifier{}
+	d.SetNotifier(recorder)
+	d.SetClusterSource(func() internaltypes.ClusterManifest {
+		return internaltypes.ClusterManifest{Utilization: internaltypes.Utilization{PercentageCPU: 40, PercentageRAM: 30}}
+	})
+
+	d.ScaleDown(testNodeName)
+	d.ScaleDown("missing")
+
+	expected := []notifier.EventType{notifier.EventStarted, notifier.EventSucceeded, notifier.EventStarted, notifier.EventFailed}
+	if len(recorder.events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), recorder.events)
+	}
+	for i, event := range recorder.events {
+		if event.Type != expected[i] || event.UtilizationBefore == nil || event.UtilizationBefore.PercentageCPU != 40 {
+			t.Errorf("Expected a %s event with the cluster utilization, got %+v", expected[i], event)
+		}
+	}
+	if succeeded := recorder.events[1]; succeeded.Node != testNodeName || succeeded.Pool != "workers" || succeeded.UtilizationAfter == nil {
+		t.Errorf("Unexpected succeeded event %+v", succeeded)
+	}
+	if failed := recorder.events[3]; failed.Node != "missing" || failed.Pool != "" || failed.Error == "" {
+		t.Errorf("Unexpected failed event %+v", failed)
+	}
+}
+
+// TestEstimatedSavings tests that drained nodes save their price until they are given back to the pods
+func TestEstimatedSavings(t *testing.T) {
+	n := node(false)
+	n.Annotations = map[string]string{internaltypes.DefaultPriceAnnotation: "0.5"}
+	fc := clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+	d := NewAPICordonDrainer(fake.NewSimpleClientset(n), nil)
+	d.SetClock(fc)
+	d.SetPriceTable(&internaltypes.PriceTable{})
+
+	d.ScaleDown(testNodeName)
+	fc.Step(2 * time.Hour)
+	if saved := d.EstimatedSavings(fc.Now()); saved != 1 {
+		t.Errorf("Expected 2 hours of savings, got %v", saved)
+	}
+
+	d.StopSaving(testNodeName)
+	fc.Step(time.Hour)
+	if saved := d.EstimatedSavings(fc.Now()); saved != 1 {
+		t.Errorf("Expected the savings to stop once uncordoned, got %v", saved)
+	}
+	if status := d.Status(fc.Now()); len(status.Savings) != 0 {
+		t.Errorf("Expected the ended savings to be left out of the status, got %+v", status.Savings)
+	}
+}
+
+type recordingAuditor struct {
+	records []audit.Record
+}
+
+func (r *recordingAuditor) Audit(record audit.Record) {
+	r.records = append(r.records, record)
+}
+
+// TestScaleDownAudit tests that scale downs are recorded with the pods they evicted and the settings in effect
+func TestScaleDownAudit(t *testing.T) {
+	web := ownedPod("web-1", "web", 0)
+	client := fake.NewSimpleClientset(node(false), &web)
+	blockingReactor(client, 0, 0)
+	fc := newNotifyingClock()
+	d := NewAPICordonDrainer(client, nil)
+	d.SetClock(fc)
+	auditor := &recordingAuditor{}
+	d.SetAuditor(auditor)
+	settings := map[string]string{"verify_timeout": "0"}
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: settings}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = fc.run(t, time.Hour, DefaultDeletionPoll, func() error {
+		d.ScaleDown(testNodeName)
+		return nil
+	})
+	d.ScaleDown("missing")
+
+	if len(auditor.records) != 2 {
+		t.Fatalf("Expected 2 records, got %+v", auditor.records)
+	}
+	succeeded, failed := auditor.records[0], auditor.records[1]
+	if succeeded.Kind != audit.KindScaleDown || succeeded.Result != audit.ResultSucceeded || succeeded.Node != testNodeName ||
+		len(succeeded.Pods) != 1 || succeeded.Pods[0] != (audit.Pod{Namespace: "default", Name: "web-1", Owner: "ReplicaSet/web"}) ||
+		succeeded.SettingsHash != d.SettingsHash() {
+		t.Errorf("Unexpected scale down record %+v", succeeded)
+	}
+	if failed.Result != audit.ResultFailed || failed.Node != "missing" || failed.Error == "" {
+		t.Errorf("Unexpected failed scale down record %+v", failed)
+	}
+
+	settings["time_gap"] = "5"
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: settings}); err != nil {
+		t.Fatal(err)
+	}
+	if d.SettingsHash() == succeeded.SettingsHash {
+		t.Errorf("Expected the settings hash to change with the settings")
+	}
+}
+
+// TestScaleDownTracing tests that a scale down is traced as a tree of cordon, drain, evict and await-deletion spans
+func TestScaleDownTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	web := ownedPod("web-1", "web", 0)
+	client := fake.NewSimpleClientset(node(false), &web)
+	blockingReactor(client, 0, 0)
+	fc := newNotifyingClock()
+	d := NewAPICordonDrainer(client, nil)
+	d.SetClock(fc)
+	if err := d.UpdateSettings(&v1.ConfigMap{Data: map[string]string{"verify_timeout": "0"}}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fc.run(t, time.Hour, DefaultDeletionPoll, func() error {
+		d.ScaleDown(testNodeName)
+		return nil
+	})
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	for name, parent := range map[string]string{"cordon": "scale-down", "drain": "scale-down", "evict": "drain", "await-deletion": "evict"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("Expected a %s span, got %+v", name, spans)
+		}
+		if span.Parent.SpanID() != spans[parent].SpanContext.SpanID() {
+			t.Errorf("Expected the %s span to be a child of the %s span", name, parent)
+		}
+	}
+	if root := spans["scale-down"]; root.Parent.IsValid() || root.Status.Code != codes.Unset {
+		t.Errorf("Unexpected scale down span %+v", root)
+	}
+	if attrs := spans["evict"].Attributes; len(attrs) != 2 || attrs[1] != attribute.String("pod", "web-1") {
+		t.Errorf("Unexpected evict span attributes %v", attrs)
+	}
+
+	exporter.Reset()
+	d.ScaleDown("missing")
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "scale-down" && span.Status.Code != codes.Error {
+			t.Errorf("Expected the failed scale down span to be an error, got %+v", span.Status)
+		}
+	}
+}
+
+// TestScaleDownExclusions tests that a node running an excluded pod or a pod of too high a
+// priority is refused before the scale down starts: the time gap isn't used up and nothing is
+// notified or audited
+func TestScaleDownExclusions(t *testing.T) {
+	threshold := int32(1000)
+	for name, exclusions := range map[string]*internaltypes.Exclusions{
+		"excluded pod": {
+			PodSelectors: []meta_v1.LabelSelector{{MatchLabels: map[string]string{"app": "web"}}},
+			BlockDrain:   true,
+		},
+		"priority threshold": {PriorityThreshold: &threshold},
+	} {
+		t.Run(name, func(t *testing.T) {
+			web := ownedPod("web-1", "web", 1000)
+			web.Labels = map[string]string{"app": "web"}
+			client := fake.NewSimpleClientset(node(false), &web)
+			d := NewAPICordonDrainer(client, nil)
+			if err := exclusions.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			d.SetExclusions(exclusions)
+			recorder, auditor := &recordingNotifier{}, &recordingAuditor{}
+			d.SetNotifier(recorder)
+			d.SetAuditor(auditor)
+
+			decision := d.AttemptDrain(testNodeName, readyCluster())
+			if decision.Allowed || !strings.Contains(decision.Reason, "web-1") {
+				t.Errorf("Expected the drain to be refused naming the pod, got %+v", decision)
+			}
+			d.ScaleDown(testNodeName)
+			if !d.LastScaleDown.IsZero() || d.LastFailedScaleDownErr != nil {
+				t.Errorf("Expected no scale down to be recorded, got %v, %v", d.LastScaleDown, d.LastFailedScaleDownErr)
+			}
+			if len(recorder.events) != 0 || len(auditor.records) != 0 {
+				t.Errorf("Expected nothing to be notified or audited, got %v and %v", recorder.events, auditor.records)
+			}
+			n, err := client.CoreV1().Nodes().Get(context.TODO(), testNodeName, meta_v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.Spec.Unschedulable {
+				t.Errorf("Expected the node not to be cordoned")
+			}
+		})
+	}
+}
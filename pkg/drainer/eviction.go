@@ -0,0 +1,266 @@
+package drainer
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	internaltypes "github.com/SAP/node-refiner/pkg/types"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// evictionOrder sorts the pods of a node in the order they should be evicted: lowest priority
+// first, and within a priority the replicas of each controller are interleaved so that the
+// replicas of one workload are spread out over the drain instead of leaving all at once
+func evictionOrder(pods []v1.Pod) []v1.Pod {
+	byPriority := map[int32]map[string][]v1.Pod{}
+	for _, p := range pods {
+		priority := internaltypes.PodPriority(&p)
+		if byPriority[priority] == nil {
+			byPriority[priority] = map[string][]v1.Pod{}
+		}
+		key := controllerKey(&p)
+		byPriority[priority][key] = append(byPriority[priority][key], p)
+	}
+
+	priorities := make([]int32, 0, len(byPriority))
+	for priority := range byPriority {
+		priorities = append(priorities, priority)
+	}
+	sort.Slice(priorities, func(i, j int) bool { return priorities[i] < priorities[j] })
+
+	ordered := make([]v1.Pod, 0, len(pods))
+	for _, priority := range priorities {
+		controllers := byPriority[priority]
+		keys := make([]string, 0, len(controllers))
+		for key, replicas := range controllers {
+			sort.Slice(replicas, func(i, j int) bool { return replicas[i].Name < replicas[j].Name })
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for round := 0; ; round++ {
+			added := false
+			for _, key := range keys {
+				if round < len(controllers[key]) {
+					ordered = append(ordered, controllers[key][round])
+					added = true
+				}
+			}
+			if !added {
+				break
+			}
+		}
+	}
+	return ordered
+}
+
+// controllerKey identifies the workload a pod belongs to, pods without a controller are their own workload
+func controllerKey(p *v1.Pod) string {
+	if ref := metav1.GetControllerOf(p); ref != nil {
+		return p.Namespace + "/" + string(ref.UID)
+	}
+	return p.Namespace + "/" + p.Name
+}
+
+// hasReplacement reports whether the controller of the pod recreates it somewhere else once evicted
+func hasReplacement(p *v1.Pod) bool {
+	ref := metav1.GetControllerOf(p)
+	return ref != nil && ref.Kind != "DaemonSet"
+}
+
+// Kinds of the controllers whose pod selector and replicas are known
+var (
+	replicaSetKind            = schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}
+	statefulSetKind           = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	jobKind                   = schema.GroupKind{Group: "batch", Kind: "Job"}
+	replicationControllerKind = schema.GroupKind{Kind: "ReplicationController"}
+)
+
+// controllerKind returns the group and kind of the controller
+func controllerKind(ref *metav1.OwnerReference) schema.GroupKind {
+	return schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind).GroupKind()
+}
+
+// newWorkload resolves the label selector of the pods of the controller of the pod, so that its
+// replicas are listed without the rest of the namespace. The pods of controllers whose kind isn't
+// known, or that are gone, are looked up among all the pods of the namespace
+func (d *APICordonDrainer) newWorkload(ctx context.Context, p *v1.Pod) (*workload, error) {
+	ref := metav1.GetControllerOf(p)
+	w := &workload{namespace: p.Namespace, ref: ref, selector: labels.Everything()}
+
+	var selector *metav1.LabelSelector
+	var err error
+	switch controllerKind(ref) {
+	case replicaSetKind:
+		var rs *appsv1.ReplicaSet
+		if rs, err = d.c.AppsV1().ReplicaSets(p.Namespace).Get(ctx, ref.Name, metav1.GetOptions{}); err == nil {
+			selector = rs.Spec.Selector
+		}
+	case statefulSetKind:
+		var sts *appsv1.StatefulSet
+		if sts, err = d.c.AppsV1().StatefulSets(p.Namespace).Get(ctx, ref.Name, metav1.GetOptions{}); err == nil {
+			selector = sts.Spec.Selector
+		}
+	case jobKind:
+		var job *batchv1.Job
+		if job, err = d.c.BatchV1().Jobs(p.Namespace).Get(ctx, ref.Name, metav1.GetOptions{}); err == nil {
+			selector = job.Spec.Selector
+		}
+	case replicationControllerKind:
+		var rc *v1.ReplicationController
+		if rc, err = d.c.CoreV1().ReplicationControllers(p.Namespace).Get(ctx, ref.Name, metav1.GetOptions{}); err == nil && len(rc.Spec.Selector) > 0 {
+			selector = &metav1.LabelSelector{MatchLabels: rc.Spec.Selector}
+		}
+	}
+	if apierrors.IsNotFound(err) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get %s", w)
+	}
+	if selector != nil {
+		if w.selector, err = metav1.LabelSelectorAsSelector(selector); err != nil {
+			return nil, errors.Wrapf(err, "invalid pod selector of %s", w)
+		}
+	}
+	return w, nil
+}
+
+// desiredReplicas returns the number of replicas the controller of the workload currently wants,
+// 0 once it is gone, and false when its kind isn't known or it doesn't tell
+func (d *APICordonDrainer) desiredReplicas(ctx context.Context, w *workload) (int, bool, error) {
+	var replicas *int32
+	var err error
+	switch controllerKind(w.ref) {
+	case replicaSetKind:
+		var rs *appsv1.ReplicaSet
+		if rs, err = d.c.AppsV1().ReplicaSets(w.namespace).Get(ctx, w.ref.Name, metav1.GetOptions{}); err == nil {
+			replicas = rs.Spec.Replicas
+		}
+	case statefulSetKind:
+		var sts *appsv1.StatefulSet
+		if sts, err = d.c.AppsV1().StatefulSets(w.namespace).Get(ctx, w.ref.Name, metav1.GetOptions{}); err == nil {
+			replicas = sts.Spec.Replicas
+		}
+	case replicationControllerKind:
+		var rc *v1.ReplicationController
+		if rc, err = d.c.CoreV1().ReplicationControllers(w.namespace).Get(ctx, w.ref.Name, metav1.GetOptions{}); err == nil {
+			replicas = rc.Spec.Replicas
+		}
+	default:
+		return 0, false, nil
+	}
+	if apierrors.IsNotFound(err) {
+		return 0, true, nil
+	}
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "cannot get %s", w)
+	}
+	if replicas == nil {
+		return 0, false, nil
+	}
+	return int(*replicas), true, nil
+}
+
+// readyReplicas counts the ready pods, not being deleted, of the workload
+func (d *APICordonDrainer) readyReplicas(ctx context.Context, w *workload) (int, error) {
+	return d.countReplicas(ctx, w, isPodReady)
+}
+
+// countReplicas counts the pods, not being deleted, that belong to the workload and match
+func (d *APICordonDrainer) countReplicas(ctx context.Context, w *workload, match func(*v1.Pod) bool) (int, error) {
+	pods, err := d.c.CoreV1().Pods(w.namespace).List(ctx, metav1.ListOptions{LabelSelector: w.selector.String()})
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot list the pods of %s", w)
+	}
+
+	count := 0
+	for i := range pods.Items {
+		replica := &pods.Items[i]
+		if owner := metav1.GetControllerOf(replica); owner == nil || owner.UID != w.ref.UID {
+			continue
+		}
+		if replica.DeletionTimestamp == nil && match(replica) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// awaitReplacement waits until the workload of an evicted pod is back to the given number of ready replicas
+func (d *APICordonDrainer) awaitReplacement(ctx context.Context, w *workload, want int, timeout time.Duration) error {
+	deadline := d.clock.After(timeout)
+	for {
+		ready, err := d.readyReplicas(ctx, w)
+		if err != nil {
+			return err
+		}
+		if ready >= want {
+			zap.S().Infow("Replacement pod is ready", "workload", w.String(), "readyReplicas", ready)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "waiting for replacement aborted")
+		case <-deadline:
+			return wait.ErrWaitTimeout
+		case <-d.clock.After(DefaultDeletionPoll):
+		}
+	}
+}
+
+// evictionBackoff is the jittered exponential backoff between retries of an eviction refused
+// by a pod disruption budget, growing from DefaultEvictionRetry up to DefaultEvictionRetryCap plus jitter
+func evictionBackoff() *wait.Backoff {
+	return &wait.Backoff{
+		Duration: DefaultEvictionRetry,
+		Factor:   2,
+		Jitter:   0.2,
+		Steps:    math.MaxInt32,
+		Cap:      DefaultEvictionRetryCap,
+	}
+}
+
+// describeBlockingPDBs names the pod disruption budgets selecting the pod, falling back
+// to the eviction error when they cannot be listed
+func (d *APICordonDrainer) describeBlockingPDBs(ctx context.Context, p *v1.Pod, evictionErr error) string {
+	pdbs, err := d.listPDBs(ctx, p.Namespace)
+	if err != nil {
+		zap.S().Warnw("Unable to list the pod disruption budgets", "namespace", p.Namespace, "error", err)
+		return evictionErr.Error()
+	}
+
+	var blocking []string
+	for _, pdb := range pdbs {
+		if pdb.selects(p) {
+			blocking = append(blocking, pdb.String())
+		}
+	}
+	if len(blocking) == 0 {
+		return evictionErr.Error()
+	}
+	return "blocked by " + strings.Join(blocking, ", ")
+}
+
+func isPodReady(p *v1.Pod) bool {
+	for _, condition := range p.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
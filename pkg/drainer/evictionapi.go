@@ -0,0 +1,180 @@
+package drainer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// Eviction API versions the drainer can evict pods with
+var (
+	EvictionV1      = policyv1.SchemeGroupVersion
+	EvictionV1beta1 = policy.SchemeGroupVersion
+)
+
+// ErrEvictionUnsupported is returned when the server serves no eviction API and
+// deleting pods instead is not enabled
+var ErrEvictionUnsupported = errors.New("eviction API not supported by the server")
+
+// DiscoverEvictionAPI asks the server which version of the eviction API it serves. Pods are
+// evicted with policy/v1beta1 until it succeeds, which servers from Kubernetes 1.25 on don't serve
+func (d *APICordonDrainer) DiscoverEvictionAPI() error {
+	gv, err := discoverEvictionVersion(d.c.Discovery())
+	if err != nil {
+		return errors.Wrap(err, "cannot discover the eviction API")
+	}
+	d.mu.Lock()
+	d.settings.evictionVersion = gv
+	d.mu.Unlock()
+	if gv.Empty() {
+		zap.S().Warnw("The server doesn't support the eviction API, pods can only be deleted if the delete fallback is enabled")
+		return nil
+	}
+	zap.S().Infow("Discovered the eviction API", "groupVersion", gv.String())
+	return nil
+}
+
+// discoverEvictionVersion returns the group version of the pods/eviction subresource, empty if the
+// server doesn't serve it. Servers that don't report the subresource's version serve the preferred
+// version of the policy group.
+func discoverEvictionVersion(client discovery.DiscoveryInterface) (schema.GroupVersion, error) {
+	groups, err := client.ServerGroups()
+	if err != nil {
+		return schema.GroupVersion{}, err
+	}
+	preferred := ""
+	for _, group := range groups.Groups {
+		if group.Name == policy.GroupName {
+			preferred = group.PreferredVersion.GroupVersion
+			break
+		}
+	}
+	if preferred == "" {
+		return schema.GroupVersion{}, nil
+	}
+
+	resources, err := client.ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		return schema.GroupVersion{}, err
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name != "pods/eviction" || resource.Kind != "Eviction" {
+			continue
+		}
+		if resource.Group != "" && resource.Version != "" {
+			return schema.GroupVersion{Group: resource.Group, Version: resource.Version}, nil
+		}
+		return schema.ParseGroupVersion(preferred)
+	}
+	return schema.GroupVersion{}, nil
+}
+
+// evictPod creates an eviction for the pod with the discovered eviction API version
+func (d *APICordonDrainer) evictPod(ctx context.Context, p *v1.Pod, gracePeriod int64) error {
+	eviction := &policy.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Namespace: p.Namespace, Name: p.Name},
+		DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod},
+	}
+	if d.currentSettings().evictionVersion != EvictionV1 {
+		return d.c.CoreV1().Pods(p.Namespace).Evict(ctx, eviction)
+	}
+
+	// The typed client only knows policy/v1beta1 evictions, the policy/v1 schema is the same
+	eviction.TypeMeta = metav1.TypeMeta{APIVersion: EvictionV1.String(), Kind: "Eviction"}
+	body, err := json.Marshal(eviction)
+	if err != nil {
+		return err
+	}
+	return d.c.CoreV1().RESTClient().Post().
+		Namespace(p.Namespace).Resource("pods").Name(p.Name).SubResource("eviction").
+		SetHeader("Content-Type", "application/json").
+		Body(body).Do(ctx).Error()
+}
+
+// deletePod deletes the pod bypassing the eviction API and its disruption budgets, like
+// kubectl drain --disable-eviction, and waits for it to be gone
+func (d *APICordonDrainer) deletePod(ctx context.Context, p *v1.Pod, gracePeriod int64, reason string) error {
+	zap.S().Warnw("Deleting Pod without eviction", "pod", p.Name, "namespace", p.Namespace, "reason", reason)
+	if d.s != nil {
+		d.s.DrainerMetrics.PodsDeleted.Inc()
+	}
+
+	uid := p.GetUID()
+	err := d.c.CoreV1().Pods(p.Namespace).Delete(ctx, p.Name, metav1.DeleteOptions{
+		GracePeriodSeconds: &gracePeriod,
+		Preconditions:      &metav1.Preconditions{UID: &uid},
+	})
+	switch {
+	// A conflict means the UID precondition failed, the pod was already replaced
+	case apierrors.IsNotFound(err), apierrors.IsConflict(err):
+		return nil
+	case err != nil:
+		return errors.Wrapf(err, "cannot delete pod %s/%s", p.GetNamespace(), p.GetName())
+	}
+	return errors.Wrapf(d.awaitDeletion(ctx, p, d.deleteTimeout()), "cannot confirm pod %s/%s was deleted", p.GetNamespace(), p.GetName())
+}
+
+// pdbSummary is the part of a pod disruption budget of either policy version needed to explain a refused eviction
+type pdbSummary struct {
+	namespace, name string
+	selector        *metav1.LabelSelector
+	// policy/v1 budgets with an empty selector select every pod of the namespace, policy/v1beta1 ones none
+	emptySelectsAll    bool
+	currentHealthy     int32
+	desiredHealthy     int32
+	disruptionsAllowed int32
+}
+
+// selects reports whether the budget applies to the pod
+func (s pdbSummary) selects(p *v1.Pod) bool {
+	selector, err := metav1.LabelSelectorAsSelector(s.selector)
+	if err != nil {
+		return false
+	}
+	if selector.Empty() {
+		return s.emptySelectsAll
+	}
+	return selector.Matches(labels.Set(p.Labels))
+}
+
+func (s pdbSummary) String() string {
+	return fmt.Sprintf("%s/%s (%d of %d desired pods healthy, %d disruptions allowed)",
+		s.namespace, s.name, s.currentHealthy, s.desiredHealthy, s.disruptionsAllowed)
+}
+
+// listPDBs lists the pod disruption budgets of a namespace in the version matching the eviction API
+func (d *APICordonDrainer) listPDBs(ctx context.Context, namespace string) ([]pdbSummary, error) {
+	var summaries []pdbSummary
+	if d.currentSettings().evictionVersion == EvictionV1 {
+		pdbs, err := d.c.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, pdb := range pdbs.Items {
+			summaries = append(summaries, pdbSummary{pdb.Namespace, pdb.Name, pdb.Spec.Selector, true,
+				pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy, pdb.Status.DisruptionsAllowed})
+		}
+		return summaries, nil
+	}
+
+	pdbs, err := d.c.PolicyV1beta1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, pdb := range pdbs.Items {
+		summaries = append(summaries, pdbSummary{pdb.Namespace, pdb.Name, pdb.Spec.Selector, false,
+			pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy, pdb.Status.DisruptionsAllowed})
+	}
+	return summaries, nil
+}
@@ -0,0 +1,37 @@
+package drainer
+
+import (
+	"fmt"
+
+	internaltypes "github.com/SAP/node-refiner/pkg/types"
+
+	"github.com/pkg/errors"
+)
+
+// SetExclusions sets the pods keeping their nodes from being drained, none when nil
+func (d *APICordonDrainer) SetExclusions(e *internaltypes.Exclusions) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.exclusions = e
+}
+
+// checkExclusions returns ErrDrainBlocked if the node runs a pod keeping it from being drained
+func (d *APICordonDrainer) checkExclusions(nodeName string) error {
+	d.mu.Lock()
+	e := d.exclusions
+	d.mu.Unlock()
+	if e == nil {
+		return nil
+	}
+
+	pods, err := d.getPods(nodeName)
+	if err != nil {
+		return errors.Wrapf(err, "cannot get pods for node %s", nodeName)
+	}
+	for i := range pods {
+		if blocker := e.DrainBlocker(&pods[i]); blocker != "" {
+			return fmt.Errorf("%w: node %s runs %s", ErrDrainBlocked, nodeName, blocker)
+		}
+	}
+	return nil
+}
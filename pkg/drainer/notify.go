@@ -0,0 +1,70 @@
+package drainer
+
+import (
+	"github.com/SAP/node-refiner/pkg/notifier"
+	internaltypes "github.com/SAP/node-refiner/pkg/types"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SetNotifier sets who is told when scale downs start, succeed and fail
+func (d *APICordonDrainer) SetNotifier(n notifier.Notifier) {
+	d.notifier = n
+}
+
+// SetClusterSource sets how the cluster utilization reported to the notifier is calculated
+func (d *APICordonDrainer) SetClusterSource(source func() internaltypes.ClusterManifest) {
+	d.clusterSource = source
+}
+
+// notifyStarted tells the notifier that the scale down of the node started, the returned
+// event is completed by notifyFinished
+func (d *APICordonDrainer) notifyStarted(nodeName string) notifier.Event {
+	if d.notifier == nil {
+		return notifier.Event{}
+	}
+	event := notifier.Event{
+		Type:              notifier.EventStarted,
+		Time:              d.clock.Now(),
+		Node:              nodeName,
+		Pool:              d.nodePool(nodeName),
+		UtilizationBefore: d.utilization(),
+	}
+	d.notifier.Notify(event)
+	return event
+}
+
+// notifyFinished tells the notifier that the scale down is over
+func (d *APICordonDrainer) notifyFinished(event notifier.Event, podsEvicted int, err error) {
+	if d.notifier == nil {
+		return
+	}
+	now := d.clock.Now()
+	event.Type = notifier.EventSucceeded
+	event.Duration = now.Sub(event.Time)
+	event.Time = now
+	event.PodsEvicted = podsEvicted
+	event.UtilizationAfter = d.utilization()
+	if err != nil {
+		event.Type = notifier.EventFailed
+		event.Error = err.Error()
+	}
+	d.notifier.Notify(event)
+}
+
+func (d *APICordonDrainer) utilization() *notifier.Utilization {
+	if d.clusterSource == nil {
+		return nil
+	}
+	cluster := d.clusterSource()
+	return &notifier.Utilization{PercentageCPU: cluster.Utilization.PercentageCPU, PercentageRAM: cluster.Utilization.PercentageRAM}
+}
+
+// nodePool returns the pool of the node, empty if unknown
+func (d *APICordonDrainer) nodePool(nodeName string) string {
+	node, err := d.c.CoreV1().Nodes().Get(d.getContext(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+	return internaltypes.NodePool(node)
+}
@@ -0,0 +1,80 @@
+package drainer
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// trackCordoned remembers that a scale down cordoned the node, until the scale down is over
+func (d *APICordonDrainer) trackCordoned(nodeName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.forgetCordonedLocked(nodeName)
+	d.cordoned = append(d.cordoned, nodeName)
+}
+
+// forgetCordoned forgets about a node whose scale down succeeded, that is no longer cordoned or no longer exists
+func (d *APICordonDrainer) forgetCordoned(nodeName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.forgetCordonedLocked(nodeName)
+}
+
+func (d *APICordonDrainer) forgetCordonedLocked(nodeName string) {
+	for i, name := range d.cordoned {
+		if name == nodeName {
+			d.cordoned = append(d.cordoned[:i], d.cordoned[i+1:]...)
+			return
+		}
+	}
+}
+
+// trackDrain registers the cancel function of the drain of a node while it runs
+func (d *APICordonDrainer) trackDrain(nodeName string, cancel context.CancelFunc) func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.drains == nil {
+		d.drains = map[string]context.CancelFunc{}
+	}
+	d.drains[nodeName] = cancel
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.drains, nodeName)
+	}
+}
+
+// CordonedNodes returns the nodes cordoned by the scale downs in progress, most recent last
+func (d *APICordonDrainer) CordonedNodes() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.cordoned...)
+}
+
+// RelievePressure aborts the most recent scale down in progress and uncordons its node, so that
+// pods that fit nowhere else can be scheduled on it again. Nodes whose scale down succeeded are
+// left alone. It returns the uncordoned node, empty if no scale down was in progress.
+func (d *APICordonDrainer) RelievePressure() (string, error) {
+	for {
+		d.mu.Lock()
+		if len(d.cordoned) == 0 {
+			d.mu.Unlock()
+			return "", nil
+		}
+		nodeName := d.cordoned[len(d.cordoned)-1]
+		cancel := d.drains[nodeName]
+		d.mu.Unlock()
+
+		if cancel != nil {
+			zap.S().Infow("Aborting the drain of the node", "node", nodeName)
+			cancel()
+		}
+		err := d.Uncordon(nodeName)
+		if errors.Is(err, ErrNodeNotFound) {
+			continue
+		}
+		return nodeName, err
+	}
+}
@@ -0,0 +1,83 @@
+package drainer
+
+import (
+	"time"
+
+	internaltypes "github.com/SAP/node-refiner/pkg/types"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Saving is a node a scale down drained and its hourly price, saved until the node is given back
+// to the pods. The node is expected to be removed by the cluster autoscaler once empty
+type Saving struct {
+	Node        string    `json:"node"`
+	Pool        string    `json:"pool,omitempty"`
+	CostPerHour float64   `json:"costPerHour"`
+	Since       time.Time `json:"since"`
+}
+
+// SetPriceTable sets how the drained nodes are priced, no savings are estimated when nil
+func (d *APICordonDrainer) SetPriceTable(pt *internaltypes.PriceTable) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prices = pt
+}
+
+// recordSaving starts estimating the savings of a drained node
+func (d *APICordonDrainer) recordSaving(nodeName string) {
+	d.mu.Lock()
+	pt := d.prices
+	d.mu.Unlock()
+	if pt == nil {
+		return
+	}
+
+	node, err := d.c.CoreV1().Nodes().Get(d.getContext(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		zap.S().Warnw("Unable to price the drained node", "node", nodeName, "error", err)
+		return
+	}
+	price, ok := pt.NodePrice(node)
+	if !ok {
+		zap.S().Debugw("The drained node has no price, no savings estimated", "node", nodeName)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.savings = append(d.savings, Saving{Node: nodeName, Pool: internaltypes.NodePool(node), CostPerHour: price, Since: d.clock.Now()})
+}
+
+// StopSaving stops estimating the savings of a drained node given back to the pods, what the
+// node saved is added to the savings of the ended scale downs
+func (d *APICordonDrainer) StopSaving(nodeName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.clock.Now()
+	ongoing := d.savings[:0]
+	for _, s := range d.savings {
+		if s.Node != nodeName {
+			ongoing = append(ongoing, s)
+			continue
+		}
+		if now.After(s.Since) {
+			d.savedBefore += s.CostPerHour * now.Sub(s.Since).Hours()
+		}
+	}
+	d.savings = ongoing
+}
+
+// EstimatedSavings returns the cost saved by the scale downs until now
+func (d *APICordonDrainer) EstimatedSavings(now time.Time) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	saved := d.savedBefore
+	for _, s := range d.savings {
+		if now.After(s.Since) {
+			saved += s.CostPerHour * now.Sub(s.Since).Hours()
+		}
+	}
+	return saved
+}
@@ -0,0 +1,157 @@
+package drainer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Settings are the drainer settings in effect, durations are formatted like 1h30m0s
+type Settings struct {
+	Enabled                      bool    `json:"enabled"`
+	TimeGap                      string  `json:"timeGap"`
+	MinimumTimeSinceLastAddition string  `json:"minimumTimeSinceLastAddition"`
+	MinimumTimeSinceNodeReady    string  `json:"minimumTimeSinceNodeReady"`
+	PodChurnThreshold            int     `json:"podChurnThreshold"`
+	PodChurnWindow               string  `json:"podChurnWindow"`
+	PodChurnCooldown             string  `json:"podChurnCooldown"`
+	MinimumNodes                 int     `json:"minimumNodes"`
+	MinimumNonTaintedNodes       int     `json:"minimumNonTaintedNodes"`
+	ExcessNodesThreshold         float64 `json:"excessNodesThreshold"`
+	MaxGracePeriod               string  `json:"maxGracePeriod"`
+	MaxConcurrentEvictions       int     `json:"maxConcurrentEvictions"`
+	WaitForReplacements          bool    `json:"waitForReplacements"`
+	ReplacementTimeout           string  `json:"replacementTimeout"`
+	DrainTimeout                 string  `json:"drainTimeout"`
+	PDBWaitTimeout               string  `json:"pdbWaitTimeout"`
+	DeleteFallbackTimeout        string  `json:"deleteFallbackTimeout"`
+	VerifyTimeout                string  `json:"verifyTimeout"`
+	VerifyReady                  bool    `json:"verifyReady"`
+	EvictionVersion              string  `json:"evictionVersion"`
+	Schedule                     string  `json:"schedule"`
+}
+
+// Cooldown postpones scale downs until it ends
+type Cooldown struct {
+	Name      string    `json:"name"`
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until"`
+	Remaining string    `json:"remaining"`
+}
+
+// Action is a scale down the drainer started
+type Action struct {
+	Node     string     `json:"node"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Status is the state of the drainer
+type Status struct {
+	Phase    string   `json:"phase"`
+	Paused   bool     `json:"paused"`
+	Settings Settings `json:"settings"`
+	// SettingsHash identifies the settings in the audit records
+	SettingsHash string `json:"settingsHash"`
+	// Cooldowns that are still running
+	Cooldowns []Cooldown `json:"cooldowns"`
+	// NextDrainWindow is the next time the schedule allows drains, nil when it never does
+	NextDrainWindow *time.Time `json:"nextDrainWindow,omitempty"`
+	// LastAction is the latest scale down, nil before the first one
+	LastAction *Action `json:"lastAction,omitempty"`
+	// Savings lists the priced nodes drained by scale downs and not given back to the pods
+	Savings []Saving `json:"savings,omitempty"`
+	// Draining lists the nodes whose drain is in progress
+	Draining []string `json:"draining"`
+	// Cordoned lists the nodes cordoned by scale downs, most recent last
+	Cordoned []string `json:"cordoned"`
+}
+
+// settingsLocked returns the settings in effect, d.mu must be held
+func (d *APICordonDrainer) settingsLocked() Settings {
+	return Settings{
+		Enabled:                      d.settings.enabled,
+		TimeGap:                      d.settings.timeGap.String(),
+		MinimumTimeSinceLastAddition: d.settings.minimumTimeSinceLastAddition.String(),
+		MinimumTimeSinceNodeReady:    d.settings.minimumTimeSinceNodeReady.String(),
+		PodChurnThreshold:            d.settings.podChurnThreshold,
+		PodChurnWindow:               d.settings.podChurnWindow.String(),
+		PodChurnCooldown:             d.settings.podChurnCooldown.String(),
+		MinimumNodes:                 d.settings.minimumNodes,
+		MinimumNonTaintedNodes:       d.settings.minimumNonTaintedNodes,
+		ExcessNodesThreshold:         d.settings.excessNodesThreshold,
+		MaxGracePeriod:               d.settings.maxGracePeriod.String(),
+		MaxConcurrentEvictions:       d.settings.maxConcurrentEvictions,
+		WaitForReplacements:          d.settings.waitForReplacements,
+		ReplacementTimeout:           d.settings.replacementTimeout.String(),
+		DrainTimeout:                 d.settings.drainTimeout.String(),
+		PDBWaitTimeout:               d.settings.pdbWaitTimeout.String(),
+		DeleteFallbackTimeout:        d.settings.deleteFallbackTimeout.String(),
+		VerifyTimeout:                d.settings.verifyTimeout.String(),
+		VerifyReady:                  d.settings.verifyReady,
+		EvictionVersion:              d.settings.evictionVersion.String(),
+		Schedule:                     d.settings.schedule.String(),
+	}
+}
+
+// SettingsHash identifies the settings in effect, it changes whenever a setting does
+func (d *APICordonDrainer) SettingsHash() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.settingsHashLocked()
+}
+
+func (d *APICordonDrainer) settingsHashLocked() string {
+	settings, _ := json.Marshal(d.settingsLocked())
+	sum := sha256.Sum256(settings)
+	return hex.EncodeToString(sum[:6])
+}
+
+// Status returns the state of the drainer at the given time
+func (d *APICordonDrainer) Status(now time.Time) Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := Status{
+		Phase:        d.phaseLocked(),
+		Paused:       d.paused,
+		Settings:     d.settingsLocked(),
+		SettingsHash: d.settingsHashLocked(),
+		Cooldowns:    []Cooldown{},
+		Draining:     []string{},
+		Cordoned:     append([]string{}, d.cordoned...),
+		Savings:      append([]Saving(nil), d.savings...),
+	}
+	for _, c := range []struct {
+		name  string
+		since time.Time
+		span  time.Duration
+	}{
+		{"node addition", d.LastNodeAddition, d.settings.minimumTimeSinceLastAddition},
+		{"node ready", d.LastNodeReady, d.settings.minimumTimeSinceNodeReady},
+		{"pod churn", d.LastPodChurn, d.settings.podChurnCooldown},
+		{"scale down", d.LastScaleDown, d.settings.timeGap},
+	} {
+		if until := c.since.Add(c.span); until.After(now) {
+			status.Cooldowns = append(status.Cooldowns, Cooldown{
+				Name: c.name, Since: c.since, Until: until, Remaining: until.Sub(now).Round(time.Second).String(),
+			})
+		}
+	}
+
+	if next, ok := d.settings.schedule.NextAllowed(now); ok {
+		status.NextDrainWindow = &next
+	}
+	if !d.lastAction.Started.IsZero() {
+		action := d.lastAction
+		status.LastAction = &action
+	}
+	for node := range d.drains {
+		status.Draining = append(status.Draining, node)
+	}
+	sort.Strings(status.Draining)
+	return status
+}
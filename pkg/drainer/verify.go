@@ -0,0 +1,112 @@
+package drainer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ErrReplacementsPending is returned when the replacements of the pods evicted from a node
+// weren't scheduled on other nodes within the verification timeout
+var ErrReplacementsPending = errors.New("replacement pods not scheduled")
+
+// workload is a controller whose pods are evicted from a node, with the selector of its pods
+// and the number of them that were scheduled before the drain
+type workload struct {
+	namespace string
+	ref       *metav1.OwnerReference
+	selector  labels.Selector
+	scheduled int
+}
+
+func (w *workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.ref.Kind, w.namespace, w.ref.Name)
+}
+
+// replacedWorkloads returns the controllers that recreate the pods of a node elsewhere once evicted
+func (d *APICordonDrainer) replacedWorkloads(ctx context.Context, pods []v1.Pod) (map[types.UID]*workload, error) {
+	workloads := map[types.UID]*workload{}
+	for i := range pods {
+		p := &pods[i]
+		// Jobs don't recreate the pods that completed, and need no more pods once done
+		if !hasReplacement(p) || controllerKind(metav1.GetControllerOf(p)) == jobKind {
+			continue
+		}
+		ref := metav1.GetControllerOf(p)
+		if _, ok := workloads[ref.UID]; ok {
+			continue
+		}
+		w, err := d.newWorkload(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		if w.scheduled, err = d.countReplicas(ctx, w, isPodScheduled); err != nil {
+			return nil, err
+		}
+		workloads[ref.UID] = w
+	}
+	return workloads, nil
+}
+
+// verifyReplacements waits until every workload has as many pods scheduled, or ready if enabled,
+// on other nodes as it had scheduled before the drain
+func (d *APICordonDrainer) verifyReplacements(ctx context.Context, nodeName string, workloads map[types.UID]*workload) error {
+	settings := d.currentSettings()
+	replaced := func(p *v1.Pod) bool {
+		if p.Spec.NodeName == nodeName || !isPodScheduled(p) {
+			return false
+		}
+		return !settings.verifyReady || isPodReady(p)
+	}
+
+	deadline := d.clock.After(settings.verifyTimeout)
+	for {
+		var pending []string
+		for _, w := range workloads {
+			count, err := d.countReplicas(ctx, w, replaced)
+			if err != nil {
+				return err
+			}
+			// A controller scaled down during the drain, for example by an autoscaler, doesn't
+			// replace every evicted pod
+			want := w.scheduled
+			desired, ok, err := d.desiredReplicas(ctx, w)
+			if err != nil {
+				return err
+			}
+			if ok && desired < want {
+				want = desired
+			}
+			if count < want {
+				pending = append(pending, fmt.Sprintf("%s (%d of %d replaced)", w, count, want))
+			}
+		}
+		if len(pending) == 0 {
+			zap.S().Infow("Replacement pods are scheduled", "node", nodeName, "workloads", len(workloads))
+			return nil
+		}
+		sort.Strings(pending)
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "verifying replacements aborted")
+		case <-deadline:
+			return fmt.Errorf("%w after %v: %s", ErrReplacementsPending, settings.verifyTimeout, strings.Join(pending, ", "))
+		case <-d.clock.After(DefaultVerifyPoll):
+		}
+	}
+}
+
+// isPodScheduled reports whether the pod was bound to a node and didn't terminate, terminated
+// pods are not replaced by their controller
+func isPodScheduled(p *v1.Pod) bool {
+	return p.Spec.NodeName != "" && p.Status.Phase != v1.PodSucceeded && p.Status.Phase != v1.PodFailed
+}
@@ -0,0 +1,313 @@
+// Package notifier tells webhooks about the scale downs of the drainer, posting either the
+// events as JSON or messages rendered from templates for chat tools
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/yaml"
+)
+
+// Default notifier settings
+const (
+	DefaultRetries   = 5
+	DefaultTimeout   = 10 * time.Second
+	DefaultQueueSize = 100
+
+	defaultRetryBackoff    = 1 * time.Second
+	defaultMaxRetryBackoff = 1 * time.Minute
+)
+
+// Formats of the webhook payloads
+const (
+	// FormatJSON posts the event as JSON
+	FormatJSON = "json"
+	// FormatChat posts {"text": <rendered template>} as understood by Slack, Mattermost or Teams
+	FormatChat = "chat"
+)
+
+// DefaultChatTemplate is the message posted to chat webhooks without a template of their own
+const DefaultChatTemplate = `{{if eq .Type "started"}}:hourglass: Draining node {{.Node}}{{if .Pool}} of pool {{.Pool}}{{end}}` +
+	`{{else if eq .Type "succeeded"}}:white_check_mark: Drained node {{.Node}}{{if .Pool}} of pool {{.Pool}}{{end}} in {{.Duration}}, {{.PodsEvicted}} pods evicted` +
+	`{{else}}:x: Failed to drain node {{.Node}}{{if .Pool}} of pool {{.Pool}}{{end}} after {{.Duration}}: {{.Error}}{{end}}` +
+	`{{with .UtilizationBefore}}, cluster CPU {{printf "%.2f" .PercentageCPU}}% RAM {{printf "%.2f" .PercentageRAM}}%{{end}}` +
+	`{{with .UtilizationAfter}}, now CPU {{printf "%.2f" .PercentageCPU}}% RAM {{printf "%.2f" .PercentageRAM}}%{{end}}`
+
+// EventType is the step of a scale down an event reports
+type EventType string
+
+// Types of the scale down events
+const (
+	EventStarted   EventType = "started"
+	EventSucceeded EventType = "succeeded"
+	EventFailed    EventType = "failed"
+)
+
+// Utilization of the resources of the cluster, in percent
+type Utilization struct {
+	PercentageCPU float64 `json:"percentageCPU"`
+	PercentageRAM float64 `json:"percentageRAM"`
+}
+
+// Event is a step of a scale down
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	Node string    `json:"node"`
+	Pool string    `json:"pool,omitempty"`
+	// PodsEvicted and Duration are set once the scale down is over
+	PodsEvicted     int           `json:"podsEvicted"`
+	Duration        time.Duration `json:"-"`
+	DurationSeconds float64       `json:"durationSeconds"`
+	Error           string        `json:"error,omitempty"`
+	// Utilization of the cluster when the scale down started and when it was over
+	UtilizationBefore *Utilization `json:"utilizationBefore,omitempty"`
+	UtilizationAfter  *Utilization `json:"utilizationAfter,omitempty"`
+}
+
+// Notifier is told about the scale downs
+type Notifier interface {
+	Notify(event Event)
+}
+
+// WebhookConfig is a webhook the events are posted to
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Format of the payload, json or chat. Defaults to chat when a template is set, to json otherwise
+	Format string `json:"format,omitempty"`
+	// Template of the chat message, a text/template rendered with the event
+	Template string `json:"template,omitempty"`
+	// Events posted to the webhook, all when empty
+	Events []EventType `json:"events,omitempty"`
+	// Headers added to the requests, like an Authorization header
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Config of the notifier
+type Config struct {
+	Webhooks []WebhookConfig `json:"webhooks"`
+	// Retries of a failed post, with an exponential backoff
+	Retries *int `json:"retries,omitempty"`
+	// Timeout of a single post
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// LoadConfig reads the notifier config from a YAML or JSON file
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read the notifier config")
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, errors.Wrapf(err, "invalid notifier config %s", path)
+	}
+	return config, nil
+}
+
+// webhook delivers the events to one URL in order
+type webhook struct {
+	config   WebhookConfig
+	template *template.Template
+	events   map[EventType]bool
+	queue    chan Event
+}
+
+// WebhookNotifier posts the events to webhooks. Every webhook has a queue of its own, so that a
+// slow or failing webhook doesn't delay the others
+type WebhookNotifier struct {
+	webhooks []*webhook
+	client   *http.Client
+	clock    clock.Clock
+	retries  int
+	backoff  wait.Backoff
+}
+
+// New validates the config and returns a notifier, the events are posted once it runs.
+// The real clock is used when c is nil
+func New(config *Config, c clock.Clock) (*WebhookNotifier, error) {
+	if c == nil {
+		c = clock.RealClock{}
+	}
+	n := &WebhookNotifier{
+		client:  &http.Client{Timeout: DefaultTimeout},
+		clock:   c,
+		retries: DefaultRetries,
+		backoff: wait.Backoff{Duration: defaultRetryBackoff, Factor: 2, Jitter: 0.1, Steps: math.MaxInt32, Cap: defaultMaxRetryBackoff},
+	}
+	if config.Retries != nil {
+		n.retries = *config.Retries
+	}
+	if config.Timeout != nil {
+		n.client.Timeout = config.Timeout.Duration
+	}
+
+	for i, wc := range config.Webhooks {
+		if wc.URL == "" {
+			return nil, errors.Errorf("webhook %d has no url", i)
+		}
+		w := &webhook{config: wc, queue: make(chan Event, DefaultQueueSize)}
+		if w.config.Format == "" {
+			w.config.Format = FormatJSON
+			if wc.Template != "" {
+				w.config.Format = FormatChat
+			}
+		}
+		switch w.config.Format {
+		case FormatJSON:
+		case FormatChat:
+			text := wc.Template
+			if text == "" {
+				text = DefaultChatTemplate
+			}
+			t, err := template.New(fmt.Sprintf("webhook %d", i)).Option("missingkey=error").Parse(text)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid template of webhook %d", i)
+			}
+			w.template = t
+		default:
+			return nil, errors.Errorf("webhook %d has an unknown format %q, expected %s or %s", i, wc.Format, FormatJSON, FormatChat)
+		}
+		if len(wc.Events) > 0 {
+			w.events = map[EventType]bool{}
+			for _, e := range wc.Events {
+				switch e {
+				case EventStarted, EventSucceeded, EventFailed:
+					w.events[e] = true
+				default:
+					return nil, errors.Errorf("webhook %d has an unknown event %q", i, e)
+				}
+			}
+		}
+		n.webhooks = append(n.webhooks, w)
+	}
+	return n, nil
+}
+
+// Notify queues the event for the webhooks interested in it, events are dropped when a queue is full
+func (n *WebhookNotifier) Notify(event Event) {
+	event.DurationSeconds = event.Duration.Seconds()
+	for _, w := range n.webhooks {
+		if w.events != nil && !w.events[event.Type] {
+			continue
+		}
+		select {
+		case w.queue <- event:
+		default:
+			zap.S().Warnw("Notification queue is full, dropping the event", "webhook", w.host(), "event", event.Type, "node", event.Node)
+		}
+	}
+}
+
+// Run posts the queued events until stopCh is closed
+func (n *WebhookNotifier) Run(stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, w := range n.webhooks {
+		go func(w *webhook) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-w.queue:
+					if err := n.deliver(ctx, w, event); err != nil {
+						zap.S().Warnw("Unable to post the notification", "webhook", w.host(), "event", event.Type, "node", event.Node, "error", err)
+					}
+				}
+			}
+		}(w)
+	}
+	<-stopCh
+}
+
+// deliver posts the event, retrying with an exponential backoff on network errors, 429 and 5xx responses
+func (n *WebhookNotifier) deliver(ctx context.Context, w *webhook, event Event) error {
+	payload, err := w.payload(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, w, payload)
+		if err == nil || !retry || attempt >= n.retries {
+			return err
+		}
+		delay := backoff.Step()
+		zap.S().Debugw("Notification failed, retrying", "webhook", w.host(), "retryIn", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-n.clock.After(delay):
+		}
+	}
+}
+
+// post sends the payload once and reports whether a failure is worth retrying
+func (n *WebhookNotifier) post(ctx context.Context, w *webhook, payload []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return false, w.redact(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return true, w.redact(err)
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	err = errors.Errorf("webhook answered %s: %s", res.Status, strings.TrimSpace(string(body)))
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500, err
+}
+
+func (w *webhook) payload(event Event) ([]byte, error) {
+	if w.template == nil {
+		return json.Marshal(event)
+	}
+	var text bytes.Buffer
+	if err := w.template.Execute(&text, event); err != nil {
+		return nil, errors.Wrap(err, "cannot render the template")
+	}
+	return json.Marshal(map[string]string{"text": text.String()})
+}
+
+// host identifies the webhook in the logs without leaking the secrets chat tools put in their URLs
+func (w *webhook) host() string {
+	u, err := url.Parse(w.config.URL)
+	if err != nil || u.Host == "" {
+		return "invalid url"
+	}
+	return u.Host
+}
+
+// redact replaces the URL in the errors of the HTTP client with the host of the webhook
+func (w *webhook) redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return errors.Errorf("%s %s: %v", urlErr.Op, w.host(), urlErr.Err)
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// webhookServer answers the given status codes in turn, then 200, and sends the bodies it received
+func webhookServer(t *testing.T, codes ...int) (*httptest.Server, <-chan string) {
+	bodies := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON payload, got %s", req.Header.Get("Content-Type"))
+		}
+		bodies <- string(body)
+		if len(codes) > 0 {
+			res.WriteHeader(codes[0])
+			codes = codes[1:]
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, bodies
+}
+
+func receive(t *testing.T, bodies <-chan string) string {
+	select {
+	case body := <-bodies:
+		return body
+	case <-time.After(10 * time.Second):
+		t.Fatal("Nothing was posted to the webhook")
+		return ""
+	}
+}
+
+func runNotifier(t *testing.T, config *Config) *WebhookNotifier {
+	n, err := New(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 10}
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	go n.Run(stopCh)
+	return n
+}
+
+// TestWebhookRetries tests that events are posted as JSON and that failures worth it are retried
+func TestWebhookRetries(t *testing.T) {
+	flaky, flakyBodies := webhookServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	invalid, invalidBodies := webhookServer(t, http.StatusBadRequest)
+	n := runNotifier(t, &Config{Webhooks: []WebhookConfig{{URL: flaky.URL}, {URL: invalid.URL}}})
+
+	n.Notify(Event{Type: EventSucceeded, Node: "node", Pool: "workers", PodsEvicted: 3, Duration: 90 * time.Second,
+		UtilizationBefore: &Utilization{PercentageCPU: 40}, UtilizationAfter: &Utilization{PercentageCPU: 50}})
+
+	for i := 0; i < 3; i++ {
+		var event Event
+		if err := json.Unmarshal([]byte(receive(t, flakyBodies)), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Node != "node" || event.Pool != "workers" || event.PodsEvicted != 3 || event.DurationSeconds != 90 ||
+			event.UtilizationAfter == nil || event.UtilizationAfter.PercentageCPU != 50 {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	}
+	receive(t, invalidBodies)
+	select {
+	case body := <-invalidBodies:
+		t.Errorf("Expected a refused event not to be retried, got %s", body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestWebhookErrorsRedacted tests that the errors of unreachable webhooks don't leak their URLs
+func TestWebhookErrorsRedacted(t *testing.T) {
+	server, _ := webhookServer(t)
+	server.Close()
+	n, err := New(&Config{Webhooks: []WebhookConfig{{URL: server.URL + "/hooks/secret-token"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := n.post(context.Background(), n.webhooks[0], []byte("{}"))
+	if !retry || err == nil {
+		t.Fatalf("Expected a failure worth retrying, got %v, %v", retry, err)
+	}
+	if strings.Contains(err.Error(), "secret-token") || !strings.Contains(err.Error(), n.webhooks[0].host()) {
+		t.Errorf("Expected the error to only name the host, got %v", err)
+	}
+}
+
+// TestChatWebhook tests that chat webhooks get the rendered template of the events they asked for
+func TestChatWebhook(t *testing.T) {
+	server, bodies := webhookServer(t)
+	n := runNotifier(t, &Config{Webhooks: []WebhookConfig{{
+		URL:      server.URL,
+		Template: "{{.Node}} {{.Type}}: {{.Error}}",
+		Events:   []EventType{EventFailed},
+	}}})
+
+	n.Notify(Event{Type: EventStarted, Node: "node"})
+	n.Notify(Event{Type: EventFailed, Node: "node", Error: "eviction blocked"})
+	if body := receive(t, bodies); body != `{"text":"node failed: eviction blocked"}` {
+		t.Errorf("Unexpected payload %s", body)
+	}
+
+	if _, err := New(&Config{Webhooks: []WebhookConfig{{URL: server.URL, Template: "{{.Missing}}"}}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, config := range []WebhookConfig{{}, {URL: server.URL, Format: "xml"}, {URL: server.URL, Events: []EventType{"done"}}} {
+		if _, err := New(&Config{Webhooks: []WebhookConfig{config}}, nil); err == nil {
+			t.Errorf("Expected %+v to be invalid", config)
+		}
+	}
+}
+
+// TestDefaultChatTemplate tests that the default message renders every event
+func TestDefaultChatTemplate(t *testing.T) {
+	n, err := New(&Config{Webhooks: []WebhookConfig{{URL: "http://localhost", Format: FormatChat}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for eventType, want := range map[EventType]string{
+		EventStarted:   "Draining node node of pool workers, cluster CPU 40.00% RAM 30.00%",
+		EventSucceeded: "Drained node node of pool workers in 1m30s, 3 pods evicted, cluster CPU 40.00% RAM 30.00%, now CPU 50.00%",
+		EventFailed:    "Failed to drain node node of pool workers after 1m30s: timed out",
+	} {
+		event := Event{Type: eventType, Node: "node", Pool: "workers", PodsEvicted: 3, Duration: 90 * time.Second,
+			UtilizationBefore: &Utilization{PercentageCPU: 40, PercentageRAM: 30}}
+		if eventType != EventStarted {
+			event.UtilizationAfter = &Utilization{PercentageCPU: 50, PercentageRAM: 30}
+		}
+		if eventType == EventFailed {
+			event.Error = "timed out"
+		}
+		payload, err := n.webhooks[0].payload(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(payload), want) {
+			t.Errorf("Expected the %s message to contain %q, got %s", eventType, want, payload)
+		}
+	}
+}
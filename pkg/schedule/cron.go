@@ -0,0 +1,161 @@
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronField is one field of a cron expression, the set of values it matches
+type cronField struct {
+	values map[int]bool
+	// any is set for fields given as * or */n, used for the day of month and day of week rule
+	any bool
+}
+
+func (f cronField) matches(v int) bool {
+	return f.values[v]
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// cronExpression is a standard 5 fields cron expression: minute, hour, day of month, month
+// and day of week, evaluated in its location
+type cronExpression struct {
+	spec                                   string
+	minute, hour, dayOfMonth, month, dayOf cronField
+	location                               *time.Location
+}
+
+// parseCron parses the 5 fields of a cron expression, with lists, ranges, steps and
+// english month and day names. Sunday is 0 or 7
+func parseCron(fields []string, location *time.Location) (*cronExpression, error) {
+	if len(fields) != 5 {
+		return nil, errors.Errorf("expected 5 cron fields, got %d", len(fields))
+	}
+
+	c := &cronExpression{spec: strings.Join(fields, " "), location: location}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, errors.Wrap(err, "invalid minute")
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, errors.Wrap(err, "invalid hour")
+	}
+	if c.dayOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, errors.Wrap(err, "invalid day of month")
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, errors.Wrap(err, "invalid month")
+	}
+	if c.dayOf, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, errors.Wrap(err, "invalid day of week")
+	}
+	if c.dayOf.values[7] {
+		c.dayOf.values[0] = true
+	}
+	return c, nil
+}
+
+func parseCronField(field string, min, max int, names map[string]int) (cronField, error) {
+	f := cronField{values: map[int]bool{}}
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return f, errors.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+			f.any = f.any || part == field
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], names); err != nil {
+				return f, err
+			}
+			if high, err = parseCronValue(bounds[1], names); err != nil {
+				return f, err
+			}
+		default:
+			value, err := parseCronValue(rangePart, names)
+			if err != nil {
+				return f, err
+			}
+			low = value
+			// A single value with a step, like 5/15, runs up to the maximum
+			if step == 1 {
+				high = value
+			}
+		}
+		if low < min || high > max || low > high {
+			return f, errors.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			f.values[v] = true
+		}
+	}
+	return f, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q", value)
+	}
+	return v, nil
+}
+
+// matchesDay follows cron: when both the day of month and the day of week are restricted,
+// a day matching either of them matches
+func (c *cronExpression) matchesDay(t time.Time) bool {
+	dayOfMonth := c.dayOfMonth.matches(t.Day())
+	dayOfWeek := c.dayOf.matches(int(t.Weekday()))
+	if c.dayOfMonth.any || c.dayOf.any {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// next returns the first time the expression matches strictly after t, false if it
+// doesn't match within the next 5 years, for example on the 30th of February
+func (c *cronExpression) next(t time.Time) (time.Time, bool) {
+	t = t.In(c.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		var skip time.Time
+		switch {
+		case !c.month.matches(int(t.Month())):
+			skip = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+		case !c.matchesDay(t):
+			skip = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+		case !c.hour.matches(t.Hour()):
+			skip = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+		case !c.minute.matches(t.Minute()):
+			skip = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+		// Wall clock times repeated by a daylight saving time change may not move forward
+		if !skip.After(t) {
+			skip = t.Add(time.Minute)
+		}
+		t = skip
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,261 @@
+// Package schedule decides when nodes may be drained from maintenance windows, given as
+// cron expressions with a duration, and freeze periods
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// The controller image has no time zone database
+	_ "time/tzdata"
+
+	"github.com/pkg/errors"
+)
+
+// timezonePrefix sets the time zone of a single window, like in CRON_TZ=Europe/Berlin 0 9 * * 1-5 8h
+const timezonePrefix = "CRON_TZ="
+
+// window starts whenever its cron expression matches and lasts for its duration
+type window struct {
+	start    *cronExpression
+	duration time.Duration
+}
+
+func (w window) String() string {
+	return fmt.Sprintf("%s%s %s %s", timezonePrefix, w.start.location, w.start.spec, w.duration)
+}
+
+// activeAt reports whether the window is open at t and when it closes, windows
+// overlapping each other are merged
+func (w window) activeAt(t time.Time) (time.Time, bool) {
+	start, ok := w.start.next(t.Add(-w.duration))
+	if !ok || start.After(t) {
+		return time.Time{}, false
+	}
+	for {
+		later, ok := w.start.next(start)
+		if !ok || later.After(t) {
+			return start.Add(w.duration), true
+		}
+		start = later
+	}
+}
+
+// freeze is a period during which no node is drained
+type freeze struct {
+	from, to time.Time
+}
+
+func (f freeze) String() string {
+	return f.from.Format(time.RFC3339) + "/" + f.to.Format(time.RFC3339)
+}
+
+// Schedule of the drains. Drains are allowed when no freeze period and no blocked window
+// is active and, if there are allowed windows, one of them is open
+type Schedule struct {
+	allowed []window
+	blocked []window
+	freezes []freeze
+}
+
+// Parse reads the windows and freeze periods, one per line or separated by semicolons.
+// Windows are a 5 fields cron expression of their start followed by their duration, like
+// "0 22 * * mon-fri 8h", optionally prefixed by CRON_TZ=<time zone>. Freeze periods are two
+// RFC3339 times or two dates separated by a slash, dates include the whole last day.
+// Times without a time zone are in the given time zone, UTC if empty
+func Parse(timezone, allowed, blocked, freezes string) (*Schedule, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid time zone %q", timezone)
+	}
+
+	s := &Schedule{}
+	if s.allowed, err = parseWindows(allowed, location); err != nil {
+		return nil, errors.Wrap(err, "invalid allowed window")
+	}
+	if s.blocked, err = parseWindows(blocked, location); err != nil {
+		return nil, errors.Wrap(err, "invalid blocked window")
+	}
+	for _, line := range splitLines(freezes) {
+		f, err := parseFreeze(line, location)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid freeze period %q", line)
+		}
+		s.freezes = append(s.freezes, f)
+	}
+	return s, nil
+}
+
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(value, func(r rune) bool { return r == '\n' || r == ';' }) {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func parseWindows(value string, location *time.Location) ([]window, error) {
+	var windows []window
+	for _, line := range splitLines(value) {
+		fields := strings.Fields(line)
+		windowLocation := location
+		if strings.HasPrefix(fields[0], timezonePrefix) {
+			var err error
+			if windowLocation, err = time.LoadLocation(strings.TrimPrefix(fields[0], timezonePrefix)); err != nil {
+				return nil, errors.Wrapf(err, "%q", line)
+			}
+			fields = fields[1:]
+		}
+		if len(fields) != 6 {
+			return nil, errors.Errorf("%q: expected a cron expression and a duration", line)
+		}
+
+		start, err := parseCron(fields[:5], windowLocation)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%q", line)
+		}
+		duration, err := time.ParseDuration(fields[5])
+		if err != nil || duration <= 0 {
+			return nil, errors.Errorf("%q: invalid duration %q", line, fields[5])
+		}
+		windows = append(windows, window{start: start, duration: duration})
+	}
+	return windows, nil
+}
+
+func parseFreeze(value string, location *time.Location) (freeze, error) {
+	bounds := strings.Split(value, "/")
+	if len(bounds) != 2 {
+		return freeze{}, errors.New("expected a start and an end separated by a slash")
+	}
+	from, _, err := parseTime(strings.TrimSpace(bounds[0]), location)
+	if err != nil {
+		return freeze{}, err
+	}
+	to, isDate, err := parseTime(strings.TrimSpace(bounds[1]), location)
+	if err != nil {
+		return freeze{}, err
+	}
+	if isDate {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		return freeze{}, errors.New("the end is not after the start")
+	}
+	return freeze{from: from, to: to}, nil
+}
+
+// parseTime parses an RFC3339 time, or a time or a date in the location
+func parseTime(value string, location *time.Location) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", value, location); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, location)
+	if err != nil {
+		return time.Time{}, false, errors.Errorf("invalid time %q", value)
+	}
+	return t, true, nil
+}
+
+// Empty reports whether the schedule allows drains at any time
+func (s *Schedule) Empty() bool {
+	return s == nil || len(s.allowed)+len(s.blocked)+len(s.freezes) == 0
+}
+
+// Blocked returns why drains are not allowed at t, empty if they are allowed
+func (s *Schedule) Blocked(t time.Time) string {
+	if s.Empty() {
+		return ""
+	}
+	for _, f := range s.freezes {
+		if !t.Before(f.from) && t.Before(f.to) {
+			return fmt.Sprintf("in the freeze period %s", f)
+		}
+	}
+	for _, w := range s.blocked {
+		if _, ok := w.activeAt(t); ok {
+			return fmt.Sprintf("in the blocked window %s", w)
+		}
+	}
+	if len(s.allowed) == 0 {
+		return ""
+	}
+	for _, w := range s.allowed {
+		if _, ok := w.activeAt(t); ok {
+			return ""
+		}
+	}
+	return "outside of the allowed windows"
+}
+
+// maxSearchSteps bounds the search for the next allowed time when blocked windows keep
+// overlapping the allowed ones
+const maxSearchSteps = 10000
+
+// NextAllowed returns the first time from t on at which drains are allowed, false if
+// drains are not allowed within the next years
+func (s *Schedule) NextAllowed(t time.Time) (time.Time, bool) {
+	if s.Empty() {
+		return t, true
+	}
+	for step := 0; step < maxSearchSteps; step++ {
+		// Skip to the end of the latest freeze or blocked window active at t
+		next := t
+		for _, f := range s.freezes {
+			if !t.Before(f.from) && t.Before(f.to) && f.to.After(next) {
+				next = f.to
+			}
+		}
+		for _, w := range s.blocked {
+			if end, ok := w.activeAt(t); ok && end.After(next) {
+				next = end
+			}
+		}
+		if next.After(t) {
+			t = next
+			continue
+		}
+
+		if len(s.allowed) == 0 {
+			return t, true
+		}
+		next = time.Time{}
+		for _, w := range s.allowed {
+			if _, ok := w.activeAt(t); ok {
+				return t, true
+			}
+			if start, ok := w.start.next(t); ok && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+		if next.IsZero() {
+			return time.Time{}, false
+		}
+		t = next
+	}
+	return time.Time{}, false
+}
+
+// String lists the windows and freeze periods of the schedule
+func (s *Schedule) String() string {
+	if s.Empty() {
+		return "always"
+	}
+	var parts []string
+	for _, w := range s.allowed {
+		parts = append(parts, "allowed "+w.String())
+	}
+	for _, w := range s.blocked {
+		parts = append(parts, "blocked "+w.String())
+	}
+	for _, f := range s.freezes {
+		parts = append(parts, "freeze "+f.String())
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,118 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC) // a Saturday
+
+	tests := []struct {
+		spec     string
+		location *time.Location
+		want     time.Time
+	}{
+		{"*/15 * * * *", time.UTC, time.Date(2021, 5, 1, 12, 15, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.UTC, time.Date(2021, 5, 3, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", berlin, time.Date(2021, 5, 3, 7, 0, 0, 0, time.UTC)},
+		{"30 2 1 jan *", time.UTC, time.Date(2022, 1, 1, 2, 30, 0, 0, time.UTC)},
+		// Either the day of month or the day of week match when both are restricted
+		{"0 0 15 * sun", time.UTC, time.Date(2021, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.UTC, time.Date(2021, 5, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		c, err := parseCron(strings.Fields(test.spec), test.location)
+		if err != nil {
+			t.Fatalf("%s: %v", test.spec, err)
+		}
+		if got, ok := c.next(from); !ok || !got.Equal(test.want) {
+			t.Errorf("%s: expected %v, got %v", test.spec, test.want, got)
+		}
+	}
+
+	c, err := parseCron(strings.Fields("0 0 30 feb *"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.next(from); ok {
+		t.Errorf("Expected the 30th of February to never match, got %v", got)
+	}
+
+	for _, spec := range []string{"60 * * * *", "* * * * * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *"} {
+		if _, err := parseCron(strings.Fields(spec), time.UTC); err == nil {
+			t.Errorf("Expected %q to be invalid", spec)
+		}
+	}
+}
+
+func TestScheduleAllowedWindows(t *testing.T) {
+	// Drains during the night on weekdays in Berlin, but not on Friday evenings
+	s, err := Parse("Europe/Berlin", "0 22 * * mon-fri 8h", "CRON_TZ=UTC 0 20 * * fri 4h", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := time.Date(2021, 5, 3, 12, 0, 0, 0, time.UTC)
+
+	if reason := s.Blocked(monday); reason == "" {
+		t.Errorf("Expected drains to be blocked at noon")
+	}
+	next, ok := s.NextAllowed(monday)
+	if want := time.Date(2021, 5, 3, 20, 0, 0, 0, time.UTC); !ok || !next.Equal(want) {
+		t.Errorf("Expected the next window at %v, got %v", want, next)
+	}
+	if reason := s.Blocked(next.Add(7 * time.Hour)); reason != "" {
+		t.Errorf("Expected drains to be allowed within the window, got %s", reason)
+	}
+	if reason := s.Blocked(next.Add(8 * time.Hour)); reason == "" {
+		t.Errorf("Expected the window to be closed after its duration")
+	}
+
+	// The Friday window is blocked until midnight UTC
+	friday := time.Date(2021, 5, 7, 12, 0, 0, 0, time.UTC)
+	next, ok = s.NextAllowed(friday)
+	if want := time.Date(2021, 5, 8, 0, 0, 0, 0, time.UTC); !ok || !next.Equal(want) {
+		t.Errorf("Expected drains to be allowed at %v, got %v", want, next)
+	}
+}
+
+func TestScheduleFreezes(t *testing.T) {
+	s, err := Parse("", "", "", "2021-12-20/2022-01-02; 2022-01-10T08:00:00Z/2022-01-10T10:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	christmas := time.Date(2021, 12, 24, 18, 0, 0, 0, time.UTC)
+	if reason := s.Blocked(christmas); reason == "" {
+		t.Errorf("Expected drains to be blocked during the freeze")
+	}
+	next, ok := s.NextAllowed(christmas)
+	if want := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC); !ok || !next.Equal(want) {
+		t.Errorf("Expected the freeze to include its last day, until %v, got %v", want, next)
+	}
+	if reason := s.Blocked(time.Date(2022, 1, 10, 10, 0, 0, 0, time.UTC)); reason != "" {
+		t.Errorf("Expected drains to be allowed at the end of the freeze, got %s", reason)
+	}
+
+	if _, err := Parse("", "", "", "2022-01-02/2021-12-20"); err == nil {
+		t.Errorf("Expected a freeze ending before it starts to be invalid")
+	}
+	if _, err := Parse("Mars/Olympus", "", "", ""); err == nil {
+		t.Errorf("Expected an unknown time zone to be invalid")
+	}
+}
+
+func TestScheduleNeverAllowed(t *testing.T) {
+	s, err := Parse("", "0 0 30 feb * 1h", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next, ok := s.NextAllowed(time.Now()); ok {
+		t.Errorf("Expected drains to never be allowed, got %v", next)
+	}
+}
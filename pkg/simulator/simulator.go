@@ -0,0 +1,146 @@
+// Package simulator replays recorded cluster snapshots through the calculation pipeline and
+// the drainer checks to estimate how many drains a set of drainer settings would have caused
+package simulator
+
+import (
+	"context"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/common"
+	"github.com/SAP/node-refiner/pkg/controller"
+	"github.com/SAP/node-refiner/pkg/drainer"
+	"github.com/SAP/node-refiner/pkg/snapshot"
+	"github.com/SAP/node-refiner/pkg/types"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Step is the outcome of the simulation for one snapshot
+type Step struct {
+	Time time.Time `json:"time"`
+	// RecordedNodes number of nodes in the snapshot
+	RecordedNodes int `json:"recordedNodes"`
+	// Nodes number of nodes left once the simulated drains are applied, after this step's drain
+	Nodes       int     `json:"nodes"`
+	ExcessNodes float64 `json:"excessNodes"`
+	Candidate   string  `json:"candidate,omitempty"`
+	Drained     bool    `json:"drained"`
+	Reason      string  `json:"reason"`
+}
+
+// Result timeline of a simulation
+type Result struct {
+	Steps  []Step `json:"steps"`
+	Drains int    `json:"drains"`
+	// DrainedNodes in the order they were drained
+	DrainedNodes []string `json:"drainedNodes"`
+}
+
+// Run replays the history, sorted by time, with a drainer configured by the settings ConfigMap
+// (nil keeps the defaults) and the pods left out by the exclusions (nil excludes none). Time is
+// virtual: every check runs at the time of its snapshot.
+// A drained node is assumed to be removed by the cluster autoscaler; it is left out of the
+// following snapshots and its pods are moved to the remaining nodes
+func Run(history []*snapshot.Snapshot, settings *corev1.ConfigMap, exclusions *types.Exclusions) (*Result, error) {
+	d := drainer.NewAPICordonDrainer(nil, nil)
+	if settings != nil {
+		if err := d.UpdateSettings(settings); err != nil {
+			return nil, errors.Wrap(err, "invalid drainer settings")
+		}
+	}
+
+	result := &Result{}
+	drained := map[string]bool{}
+	// Pods already recorded as created, a pod is in every snapshot until it is deleted
+	seen := map[string]bool{}
+	for _, snap := range history {
+		now := snap.Time
+		s := withoutDrainedNodes(snap, drained)
+
+		d.SetLastNodeAddition(s.LastNodeAddition())
+		d.SetLastNodeReady(s.LastNodeReady())
+		for _, created := range s.PodCreations(seen) {
+			d.RecordPodChurn(created)
+		}
+
+		nodesMap, podsMap := s.Maps()
+		analysis := controller.AnalyzeContext(context.Background(), nodesMap, podsMap, exclusions)
+
+		step := Step{
+			Time:          now,
+			RecordedNodes: len(snap.Nodes),
+			Nodes:         len(s.Nodes),
+			ExcessNodes:   analysis.Cluster.ExcessNodes,
+		}
+		if analysis.Candidate == nil {
+			step.Reason = analysis.CandidateErr.Error()
+			result.Steps = append(result.Steps, step)
+			continue
+		}
+
+		step.Candidate = analysis.Candidate.Node.Name
+		decision := d.EvaluateDrainAt(now, &analysis.Cluster)
+		step.Reason = decision.Reason
+		if decision.Allowed {
+			d.SetLastScaleDown(now)
+			drained[step.Candidate] = true
+			step.Drained = true
+			step.Nodes--
+			result.Drains++
+			result.DrainedNodes = append(result.DrainedNodes, step.Candidate)
+		}
+		result.Steps = append(result.Steps, step)
+	}
+	return result, nil
+}
+
+// withoutDrainedNodes returns a copy of the snapshot without the drained nodes, their pods
+// are moved one by one to the schedulable node with the most free CPU
+func withoutDrainedNodes(snap *snapshot.Snapshot, drained map[string]bool) *snapshot.Snapshot {
+	if len(drained) == 0 {
+		return snap
+	}
+
+	s := &snapshot.Snapshot{Time: snap.Time, ConfigMaps: snap.ConfigMaps}
+	freeCPU := map[string]int64{}
+	for i := range snap.Nodes {
+		node := snap.Nodes[i]
+		if drained[node.Name] {
+			continue
+		}
+		s.Nodes = append(s.Nodes, node)
+		if !common.CheckForTaints(&node) {
+			freeCPU[node.Name] = node.Status.Allocatable.Cpu().MilliValue()
+		}
+	}
+
+	var displaced []corev1.Pod
+	for i := range snap.Pods {
+		pod := snap.Pods[i]
+		if drained[pod.Spec.NodeName] {
+			displaced = append(displaced, pod)
+			continue
+		}
+		s.Pods = append(s.Pods, pod)
+		if _, ok := freeCPU[pod.Spec.NodeName]; ok && types.IsActivePod(&pod) {
+			freeCPU[pod.Spec.NodeName] -= types.CreatePodMetricsFromPodObj(&pod).ReqCPU.MilliValue()
+		}
+	}
+
+	for _, pod := range displaced {
+		target := ""
+		for name, free := range freeCPU {
+			if target == "" || free > freeCPU[target] || (free == freeCPU[target] && name < target) {
+				target = name
+			}
+		}
+		if target == "" {
+			// Nowhere to go, the pod would stay pending
+			continue
+		}
+		pod.Spec.NodeName = target
+		freeCPU[target] -= types.CreatePodMetricsFromPodObj(&pod).ReqCPU.MilliValue()
+		s.Pods = append(s.Pods, pod)
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/snapshot"
+	"github.com/SAP/node-refiner/pkg/types"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+var start = time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+
+func testSnapshot(at time.Time, nodes int) *snapshot.Snapshot {
+	s := &snapshot.Snapshot{Time: at}
+	for i := 0; i < nodes; i++ {
+		name := fmt.Sprintf("node-%d", i)
+		node := v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: name, CreationTimestamp: meta_v1.NewTime(start.Add(-24 * time.Hour))}}
+		node.Status.Allocatable = v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("8Gi")}
+		node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue, LastTransitionTime: node.CreationTimestamp}}
+		s.Nodes = append(s.Nodes, node)
+
+		// Every node but the last one is half used
+		if i == nodes-1 {
+			continue
+		}
+		s.Pods = append(s.Pods, v1.Pod{
+			ObjectMeta: meta_v1.ObjectMeta{Name: "pod-" + name, Namespace: "default"},
+			Spec: v1.PodSpec{NodeName: name, Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("4Gi")}}}}},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		})
+	}
+	return s
+}
+
+func settings(data map[string]string) *v1.ConfigMap {
+	return &v1.ConfigMap{Data: data}
+}
+
+// TestRunHonoursTimeGap tests that drains are spaced by the time gap on the virtual clock
+func TestRunHonoursTimeGap(t *testing.T) {
+	var history []*snapshot.Snapshot
+	for i := 0; i < 4; i++ {
+		history = append(history, testSnapshot(start.Add(time.Duration(i)*10*time.Minute), 6))
+	}
+
+	result, err := Run(history, settings(map[string]string{
+		"time_gap":                  "15",
+		"time_since_last_addition":  "60",
+		"excess_nodes_threshold":    "1",
+		"minimum_nodes":             "2",
+		"minimum_non_tainted_nodes": "2",
+	}), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	drained := []bool{true, false, true, false}
+	for i, step := range result.Steps {
+		if step.Drained != drained[i] {
+			t.Errorf("Step %d: expected drained=%v, got %+v", i, drained[i], step)
+		}
+	}
+	if result.Drains != 2 {
+		t.Errorf("Expected 2 drains, got %d", result.Drains)
+	}
+	if last := result.Steps[3]; last.RecordedNodes != 6 || last.Nodes != 4 {
+		t.Errorf("Expected 4 nodes left out of 6 recorded, got %+v", last)
+	}
+}
+
+// TestRunMovesPodsOfDrainedNodes tests that the pods of a drained node still count in the cluster utilization
+func TestRunMovesPodsOfDrainedNodes(t *testing.T) {
+	var history []*snapshot.Snapshot
+	for _, at := range []time.Time{start, start.Add(time.Hour)} {
+		s := testSnapshot(at, 4)
+		pod := s.Pods[0]
+		pod.Name, pod.Spec.NodeName = "pod-node-3", "node-3"
+		s.Pods = append(s.Pods, pod)
+		history = append(history, s)
+	}
+
+	result, err := Run(history, settings(map[string]string{
+		"time_gap":               "10",
+		"excess_nodes_threshold": "1",
+		"minimum_nodes":          "2",
+	}), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 16 CPUs for 8 requested: 2 excess nodes, then 12 CPUs for the same 8 requested: 1
+	if result.Steps[0].ExcessNodes != 2 || result.Steps[1].ExcessNodes != 1 {
+		t.Errorf("Expected 2 then 1 excess nodes, got %+v", result.Steps)
+	}
+}
+
+// TestRunWaitsAfterNodeAddition tests that no drain happens right after a node joined
+func TestRunWaitsAfterNodeAddition(t *testing.T) {
+	s := testSnapshot(start, 6)
+	s.Nodes[0].CreationTimestamp = meta_v1.NewTime(start.Add(-5 * time.Minute))
+
+	result, err := Run([]*snapshot.Snapshot{s}, settings(map[string]string{"excess_nodes_threshold": "1"}), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Drains != 0 {
+		t.Errorf("Expected no drain, got %+v", result.Steps)
+	}
+}
+
+// TestRunRecordsPodsOnce tests that the pods seen in several snapshots count once in the pod churn
+func TestRunRecordsPodsOnce(t *testing.T) {
+	var history []*snapshot.Snapshot
+	for i := 0; i < 3; i++ {
+		s := testSnapshot(start.Add(time.Duration(i)*time.Minute), 6)
+		for j := range s.Pods {
+			s.Pods[j].UID = k8stypes.UID(s.Pods[j].Name)
+			s.Pods[j].CreationTimestamp = meta_v1.NewTime(start.Add(-time.Minute))
+		}
+		history = append(history, s)
+	}
+
+	// 5 pods in 3 snapshots, 15 recorded pods would be a churn
+	result, err := Run(history, settings(map[string]string{"pod_churn_threshold": "10"}), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, step := range result.Steps {
+		if strings.Contains(step.Reason, "pods were created or deleted") {
+			t.Errorf("Step %d: unexpected pod churn, %s", i, step.Reason)
+		}
+	}
+}
+
+// TestRunHonoursExclusions tests that a node running a pod excluded from drains is not the candidate
+func TestRunHonoursExclusions(t *testing.T) {
+	s := testSnapshot(start, 6)
+	s.Pods = append(s.Pods, v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "agent", Namespace: "monitoring"},
+		Spec:       v1.PodSpec{NodeName: "node-5", Containers: []v1.Container{{}}},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	})
+	history := []*snapshot.Snapshot{s}
+	data := map[string]string{"excess_nodes_threshold": "1"}
+
+	result, err := Run(history, settings(data), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if candidate := result.Steps[0].Candidate; candidate != "node-5" {
+		t.Fatalf("Expected the empty node-5 to be the candidate, got %q", candidate)
+	}
+
+	exclusions := &types.Exclusions{DeniedNamespaces: []string{"monitoring"}, BlockDrain: true}
+	if err := exclusions.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	result, err = Run(history, settings(data), exclusions)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if candidate := result.Steps[0].Candidate; candidate == "" || candidate == "node-5" {
+		t.Errorf("Expected a candidate other than node-5, got %+v", result.Steps[0])
+	}
+}
@@ -0,0 +1,171 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// object is the part of a serialized kubernetes object needed to route it
+type object struct {
+	Kind  string            `json:"kind"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// LoadDump reads a snapshot from the output of `kubectl get nodes,pods -A -o json|yaml`
+// or `kubectl cluster-info dump`, either printed to a file or written to a directory with
+// --output-directory. ConfigMaps found in the dump are kept so the drainer settings can be read.
+// The modification time of the dump is used as the time of the snapshot
+func LoadDump(path string) (*Snapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{Time: info.ModTime()}
+	if info.IsDir() {
+		err = filepath.Walk(path, func(file string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(file)) {
+			case ".json", ".yaml", ".yml":
+				return s.loadFile(file)
+			}
+			return nil
+		})
+	} else {
+		err = s.loadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Nodes) == 0 {
+		return nil, errors.Errorf("no nodes found in %s", path)
+	}
+	return s, nil
+}
+
+func (s *Snapshot) loadFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(s.decode(data), "cannot read dump %s", file)
+}
+
+// decode reads every object of a dump. JSON dumps may hold several documents separated
+// by other output, like the container logs printed by `kubectl cluster-info dump`,
+// which is skipped. Anything else is read as a stream of YAML documents
+func (s *Snapshot) decode(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] != '{' && trimmed[0] != '[' {
+		decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+		for {
+			var raw json.RawMessage
+			err := decoder.Decode(&raw)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := s.add(raw, ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	for offset := 0; offset < len(data); {
+		start := nextDocument(data, offset)
+		if start < 0 {
+			return nil
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data[start:]))
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			// Not a JSON document after all, skip the line
+			offset = start + 1
+			continue
+		}
+		if err := s.add(raw, ""); err != nil {
+			return err
+		}
+		offset = start + int(decoder.InputOffset())
+	}
+	return nil
+}
+
+// nextDocument returns the offset of the next line starting with a JSON object, -1 if none
+func nextDocument(data []byte, offset int) int {
+	for i := offset; i < len(data); i++ {
+		if data[i] == '{' && (i == 0 || data[i-1] == '\n') {
+			return i
+		}
+	}
+	return -1
+}
+
+// add routes a serialized object to the snapshot, lists are flattened and unrelated kinds ignored.
+// Items of typed lists like NodeList don't carry their kind, defaultKind is used for them
+func (s *Snapshot) add(raw json.RawMessage, defaultKind string) error {
+	if len(raw) == 0 || raw[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return err
+		}
+		return s.addAll(items, "")
+	}
+
+	var obj object
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return err
+	}
+	if obj.Kind == "" {
+		obj.Kind = defaultKind
+	}
+
+	switch obj.Kind {
+	case "List":
+		return s.addAll(obj.Items, "")
+	case "NodeList", "PodList", "ConfigMapList":
+		return s.addAll(obj.Items, strings.TrimSuffix(obj.Kind, "List"))
+	case "Node":
+		var node corev1.Node
+		if err := json.Unmarshal(raw, &node); err != nil {
+			return err
+		}
+		s.Nodes = append(s.Nodes, node)
+	case "Pod":
+		var pod corev1.Pod
+		if err := json.Unmarshal(raw, &pod); err != nil {
+			return err
+		}
+		s.Pods = append(s.Pods, pod)
+	case "ConfigMap":
+		var cm corev1.ConfigMap
+		if err := json.Unmarshal(raw, &cm); err != nil {
+			return err
+		}
+		s.ConfigMaps = append(s.ConfigMaps, cm)
+	}
+	return nil
+}
+
+func (s *Snapshot) addAll(items []json.RawMessage, defaultKind string) error {
+	for _, item := range items {
+		if err := s.add(item, defaultKind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
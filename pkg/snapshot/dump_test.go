@@ -0,0 +1,105 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const clusterInfoDump = `{
+    "kind": "NodeList",
+    "apiVersion": "v1",
+    "items": [
+        {"metadata": {"name": "node-a"}, "status": {"allocatable": {"cpu": "2", "memory": "4Gi"}}}
+    ]
+}
+==== START logs for container app of pod default/web ====
+{"level":"info","msg":"not a kubernetes object"}
+==== END logs for container app of pod default/web ====
+{
+    "kind": "PodList",
+    "apiVersion": "v1",
+    "items": [
+        {"metadata": {"name": "web", "namespace": "default"}, "spec": {"nodeName": "node-a"}, "status": {"phase": "Running"}},
+        {"metadata": {"name": "job", "namespace": "default"}, "spec": {"nodeName": "node-a"}, "status": {"phase": "Succeeded"}}
+    ]
+}
+`
+
+const kubectlGetYAML = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: node-a
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: web
+    namespace: default
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: node-refiner-cm
+    namespace: node-refiner
+  data:
+    drainer_enabled: "false"
+`
+
+func writeDump(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "dump")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoadClusterInfoDump tests reading typed lists interleaved with container logs
+func TestLoadClusterInfoDump(t *testing.T) {
+	s, err := LoadDump(writeDump(t, clusterInfoDump))
+	if err != nil {
+		t.Fatalf("Unexpected error loading dump: %v", err)
+	}
+	if len(s.Nodes) != 1 || len(s.Pods) != 2 {
+		t.Fatalf("Expected 1 node and 2 pods, got %d nodes and %d pods", len(s.Nodes), len(s.Pods))
+	}
+
+	nodesMap, podsMap := s.Maps()
+	if _, ok := nodesMap["node-a"]; !ok {
+		t.Errorf("Expected node-a in the nodes map, got %v", nodesMap)
+	}
+	if _, ok := podsMap["default/web"]; !ok || len(podsMap) != 1 {
+		t.Errorf("Expected only the running pod in the pods map, got %v", podsMap)
+	}
+}
+
+// TestLoadKubectlGetYAML tests reading a YAML list holding the settings ConfigMap
+func TestLoadKubectlGetYAML(t *testing.T) {
+	s, err := LoadDump(writeDump(t, kubectlGetYAML))
+	if err != nil {
+		t.Fatalf("Unexpected error loading dump: %v", err)
+	}
+	if len(s.Nodes) != 1 || len(s.Pods) != 1 {
+		t.Fatalf("Expected 1 node and 1 pod, got %d nodes and %d pods", len(s.Nodes), len(s.Pods))
+	}
+
+	cm, err := s.ConfigMap("node-refiner-cm", "")
+	if err != nil || cm == nil || cm.Data["drainer_enabled"] != "false" {
+		t.Errorf("Expected the settings ConfigMap, got %v (%v)", cm, err)
+	}
+}
+
+// TestLoadDumpWithoutNodes tests that a dump without nodes is rejected
+func TestLoadDumpWithoutNodes(t *testing.T) {
+	if _, err := LoadDump(writeDump(t, `{"kind": "PodList", "items": []}`)); err == nil {
+		t.Error("Expected an error for a dump without nodes")
+	}
+}
@@ -0,0 +1,144 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// maxLineSize bounds a single history line, a snapshot of a large cluster easily exceeds the bufio default
+const maxLineSize = 256 * 1024 * 1024
+
+// gzipMagic are the first bytes of a gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReadHistory reads a history file: one JSON snapshot per line, optionally gzip compressed.
+// Extra fields on a line are ignored so richer records can be read back as snapshots
+func ReadHistory(r io.Reader) ([]*Snapshot, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	var history []*Snapshot
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var s Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		history = append(history, &s)
+	}
+	// A file still being recorded ends without the gzip footer
+	if err := scanner.Err(); err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return history, nil
+}
+
+// isHistory checks whether a file starts like a history file rather than a dump
+func isHistory(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	head = head[:n]
+	if bytes.HasPrefix(head, gzipMagic) {
+		return true, nil
+	}
+	// Snapshots are serialized with the time first, dumps start with kind or apiVersion
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte(`{"time":`)), nil
+}
+
+// readHistoryFile reads a history file, returns nil if the file is not a history file
+func readHistoryFile(path string) ([]*Snapshot, error) {
+	ok, err := isHistory(path)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snapshots, err := ReadHistory(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read history %s", path)
+	}
+	return snapshots, nil
+}
+
+// Load reads snapshots from history files and dumps, a dump being a single snapshot taken at
+// its modification time. A directory holding files written by a Recorder is read as history,
+// any other directory as a dump. The snapshots are returned sorted by time
+func Load(paths ...string) ([]*Snapshot, error) {
+	var history []*Snapshot
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.IsDir() {
+			files, err := historyFiles(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, fi := range files {
+				snapshots, err := readHistoryFile(filepath.Join(path, fi.Name()))
+				if err != nil {
+					return nil, err
+				}
+				history = append(history, snapshots...)
+			}
+			if len(files) > 0 {
+				continue
+			}
+		} else {
+			snapshots, err := readHistoryFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if snapshots != nil {
+				history = append(history, snapshots...)
+				continue
+			}
+		}
+
+		s, err := LoadDump(path)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, s)
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Time.Before(history[j].Time)
+	})
+	return history, nil
+}
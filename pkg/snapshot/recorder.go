@@ -0,0 +1,231 @@
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/types"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Names of the files written by the recorder, the timestamp keeps them in chronological order
+const (
+	historyFilePrefix = "node-refiner-"
+	historyFileSuffix = ".jsonl.gz"
+	historyTimeFormat = "20060102T150405.000Z"
+)
+
+// Default recorder bounds
+const (
+	DefaultMaxFileSize  int64 = 64 * 1024 * 1024
+	DefaultMaxFileAge         = 24 * time.Hour
+	DefaultMaxAge             = 7 * 24 * time.Hour
+	DefaultMaxTotalSize int64 = 1024 * 1024 * 1024
+)
+
+// Record is one iteration of the calculation loop: its input snapshot, the computed
+// utilization, the chosen candidate and the drainer decision. It reads back as a Snapshot
+type Record struct {
+	Snapshot
+	Cluster     types.ClusterReport `json:"cluster"`
+	Utilization []types.NodeReport  `json:"utilization"`
+	Candidate   string              `json:"candidate,omitempty"`
+	Decision    *Decision           `json:"decision,omitempty"`
+}
+
+// Decision of the drainer recorded along the snapshot
+type Decision struct {
+	Drain  bool   `json:"drain"`
+	Reason string `json:"reason"`
+}
+
+// RecorderOptions bounds the files written by a Recorder, zero values disable a bound
+type RecorderOptions struct {
+	// Dir the history files are written to
+	Dir string
+	// MaxFileSize compressed size after which a new file is started
+	MaxFileSize int64
+	// MaxFileAge time after which a new file is started
+	MaxFileAge time.Duration
+	// MaxAge time after which a file is deleted
+	MaxAge time.Duration
+	// MaxTotalSize of all the files, the oldest are deleted beyond it
+	MaxTotalSize int64
+}
+
+// Recorder appends records to rotating gzip compressed JSON-lines files that can be read
+// back with Load or ReadHistory
+type Recorder struct {
+	opts RecorderOptions
+
+	mu      sync.Mutex
+	file    *os.File
+	size    *countingWriter
+	gz      *gzip.Writer
+	opened  time.Time
+	current string
+}
+
+// countingWriter counts the bytes written to the underlying file
+type countingWriter struct {
+	f *os.File
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// NewRecorder creates the history directory if needed and returns a Recorder writing to it
+func NewRecorder(opts RecorderOptions) (*Recorder, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("the recorder needs a directory")
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "cannot create history directory")
+	}
+	return &Recorder{opts: opts}, nil
+}
+
+// Record appends a record to the current file, rotating it first if it is full or too old.
+// Every record is flushed so the file is readable while it is being written
+func (r *Recorder) Record(record *Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.needsRotation(record.Time) {
+		if err := r.rotate(record.Time); err != nil {
+			return err
+		}
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := r.gz.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return r.gz.Flush()
+}
+
+// Close finishes the current file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeFile()
+}
+
+func (r *Recorder) needsRotation(now time.Time) bool {
+	if r.file == nil {
+		return true
+	}
+	if r.opts.MaxFileSize > 0 && r.size.n >= r.opts.MaxFileSize {
+		return true
+	}
+	return r.opts.MaxFileAge > 0 && now.Sub(r.opened) >= r.opts.MaxFileAge
+}
+
+func (r *Recorder) rotate(now time.Time) error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+
+	name := filepath.Join(r.opts.Dir, historyFilePrefix+now.UTC().Format(historyTimeFormat)+historyFileSuffix)
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "cannot open history file")
+	}
+	r.file = f
+	r.size = &countingWriter{f: f}
+	r.gz = gzip.NewWriter(r.size)
+	r.opened = now
+	r.current = name
+	zap.S().Infow("Recording cluster snapshots", "file", name)
+
+	r.prune(now)
+	return nil
+}
+
+func (r *Recorder) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.gz.Close()
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.file = nil
+	return err
+}
+
+// prune deletes the files that are too old, then the oldest files until the total size fits
+func (r *Recorder) prune(now time.Time) {
+	files, err := historyFiles(r.opts.Dir)
+	if err != nil {
+		zap.S().Warnw("Unable to list history files", "error", err)
+		return
+	}
+
+	var kept []os.FileInfo
+	for _, fi := range files {
+		path := filepath.Join(r.opts.Dir, fi.Name())
+		if path != r.current && r.opts.MaxAge > 0 && now.Sub(fi.ModTime()) > r.opts.MaxAge {
+			r.remove(path)
+			continue
+		}
+		kept = append(kept, fi)
+	}
+
+	if r.opts.MaxTotalSize <= 0 {
+		return
+	}
+	var total int64
+	for _, fi := range kept {
+		total += fi.Size()
+	}
+	for _, fi := range kept {
+		path := filepath.Join(r.opts.Dir, fi.Name())
+		if total <= r.opts.MaxTotalSize || path == r.current {
+			break
+		}
+		r.remove(path)
+		total -= fi.Size()
+	}
+}
+
+func (r *Recorder) remove(path string) {
+	if err := os.Remove(path); err != nil {
+		zap.S().Warnw("Unable to delete history file", "file", path, "error", err)
+		return
+	}
+	zap.S().Infow("Deleted history file", "file", path)
+}
+
+// historyFiles lists the files written by a recorder, oldest first
+func historyFiles(dir string) ([]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []os.FileInfo
+	for _, fi := range entries {
+		if !fi.IsDir() && strings.HasPrefix(fi.Name(), historyFilePrefix) && strings.HasSuffix(fi.Name(), historyFileSuffix) {
+			files = append(files, fi)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	return files, nil
+}
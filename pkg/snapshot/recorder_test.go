@@ -0,0 +1,88 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testRecord(at time.Time) *Record {
+	return &Record{
+		Snapshot: Snapshot{
+			Time:  at,
+			Nodes: []v1.Node{{ObjectMeta: meta_v1.ObjectMeta{Name: "node-a"}}},
+			Pods:  []v1.Pod{{ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "node-a"}}},
+		},
+		Candidate: "node-a",
+		Decision:  &Decision{Reason: "drainer is disabled based on the provided configuration"},
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// TestRecorderReadBack tests that records can be loaded back as snapshots while the file is still open
+func TestRecorderReadBack(t *testing.T) {
+	dir := tempDir(t)
+	r, err := NewRecorder(RecorderOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := r.Record(testRecord(start.Add(time.Duration(i) * time.Minute))); err != nil {
+			t.Fatalf("Unexpected error recording: %v", err)
+		}
+	}
+
+	history, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error loading history: %v", err)
+	}
+	if len(history) != 3 || !history[2].Time.Equal(start.Add(2*time.Minute)) || len(history[0].Pods) != 1 {
+		t.Errorf("Expected 3 snapshots in order, got %+v", history)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if history, err = Load(dir); err != nil || len(history) != 3 {
+		t.Errorf("Expected 3 snapshots once closed, got %d (%v)", len(history), err)
+	}
+}
+
+// TestRecorderRotation tests rotating by file age and pruning by total size
+func TestRecorderRotation(t *testing.T) {
+	dir := tempDir(t)
+	r, err := NewRecorder(RecorderOptions{Dir: dir, MaxFileAge: time.Hour, MaxTotalSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	start := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := r.Record(testRecord(start.Add(time.Duration(i) * time.Hour))); err != nil {
+			t.Fatalf("Unexpected error recording: %v", err)
+		}
+	}
+
+	files, err := historyFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every file but the current one is over the total size
+	if len(files) != 1 || files[0].Name() != "node-refiner-20220801T140000.000Z.jsonl.gz" {
+		t.Errorf("Expected only the newest file to be kept, got %v", files)
+	}
+}
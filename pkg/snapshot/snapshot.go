@@ -0,0 +1,118 @@
+// Package snapshot captures the nodes and pods of a cluster at a point in time so the
+// calculation pipeline can run on them without an API server
+package snapshot
+
+import (
+	"context"
+	"time"
+
+	"github.com/SAP/node-refiner/pkg/types"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Snapshot the state of the cluster the calculation pipeline needs
+type Snapshot struct {
+	Time       time.Time          `json:"time"`
+	Nodes      []corev1.Node      `json:"nodes"`
+	Pods       []corev1.Pod       `json:"pods"`
+	ConfigMaps []corev1.ConfigMap `json:"configMaps,omitempty"`
+}
+
+// FromCluster takes a snapshot of the nodes and the active pods of a live cluster
+func FromCluster(ctx context.Context, client kubernetes.Interface) (*Snapshot, error) {
+	nodeList, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list nodes")
+	}
+	podList, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{FieldSelector: types.ActivePodsFieldSelector})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list pods")
+	}
+
+	return &Snapshot{
+		Time:  time.Now(),
+		Nodes: nodeList.Items,
+		Pods:  podList.Items,
+	}, nil
+}
+
+// Maps builds the maps of NodeManifest and PodManifest the calculation pipeline works on,
+// pods that do not occupy resources anymore are left out. Pods are keyed by namespace/name
+func (s *Snapshot) Maps() (map[string]types.NodeManifest, map[string]types.PodManifest) {
+	nodesMap := make(map[string]types.NodeManifest, len(s.Nodes))
+	for i := range s.Nodes {
+		node := &s.Nodes[i]
+		nodesMap[node.Name] = types.NewNodeManifest(node)
+	}
+
+	podsMap := make(map[string]types.PodManifest, len(s.Pods))
+	for i := range s.Pods {
+		pod := &s.Pods[i]
+		if !types.IsActivePod(pod) {
+			continue
+		}
+		podsMap[pod.Namespace+"/"+pod.Name] = types.NewPodManifest(pod)
+	}
+
+	return nodesMap, podsMap
+}
+
+// LastNodeAddition returns the creation time of the newest node
+func (s *Snapshot) LastNodeAddition() time.Time {
+	var last time.Time
+	for i := range s.Nodes {
+		if last.Before(s.Nodes[i].CreationTimestamp.Time) {
+			last = s.Nodes[i].CreationTimestamp.Time
+		}
+	}
+	return last
+}
+
+// PodCreations returns the creation times of the pods not in seen and adds them to it, so that
+// pods found in several snapshots, or twice in a dump, are counted once. Pods are identified by
+// their UID, by namespace/name when they have none
+func (s *Snapshot) PodCreations(seen map[string]bool) []time.Time {
+	var created []time.Time
+	for i := range s.Pods {
+		pod := &s.Pods[i]
+		key := string(pod.UID)
+		if key == "" {
+			key = pod.Namespace + "/" + pod.Name
+		}
+		if !seen[key] {
+			seen[key] = true
+			created = append(created, pod.CreationTimestamp.Time)
+		}
+	}
+	return created
+}
+
+// LastNodeReady returns the time a node last became ready
+func (s *Snapshot) LastNodeReady() time.Time {
+	var last time.Time
+	for i := range s.Nodes {
+		if since, ready := types.NodeReadySince(&s.Nodes[i]); ready && last.Before(since) {
+			last = since
+		}
+	}
+	return last
+}
+
+// ConfigMap returns the ConfigMap with the given name, in any namespace if namespace is empty
+func (s *Snapshot) ConfigMap(name, namespace string) (*corev1.ConfigMap, error) {
+	var found *corev1.ConfigMap
+	for i := range s.ConfigMaps {
+		cm := &s.ConfigMaps[i]
+		if cm.Name != name || (namespace != "" && cm.Namespace != namespace) {
+			continue
+		}
+		if found != nil {
+			return nil, errors.Errorf("found several ConfigMaps named %s, please set the namespace", name)
+		}
+		found = cm
+	}
+	return found, nil
+}
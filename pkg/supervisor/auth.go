@@ -0,0 +1,132 @@
+package supervisor
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	// ErrUnauthenticated is returned when a request carries no valid bearer token
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned when the authenticated user may not send the request
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Authenticator decides whether a request may be served and returns who sent it
+type Authenticator interface {
+	Authenticate(req *http.Request) (string, error)
+}
+
+// TokenAuthenticator accepts the requests carrying a static bearer token
+type TokenAuthenticator struct {
+	Token string
+}
+
+// Authenticate implements Authenticator
+func (a TokenAuthenticator) Authenticate(req *http.Request) (string, error) {
+	token, ok := bearerToken(req)
+	if !ok || a.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) != 1 {
+		return "", ErrUnauthenticated
+	}
+	return "token", nil
+}
+
+// KubernetesAuthenticator authenticates the bearer token with a TokenReview and authorizes the
+// request with a SubjectAccessReview on its path, as a non-resource URL with the lower case
+// HTTP method as verb. For example a ClusterRole granting the verb post on the non-resource
+// URL /api/v1/admin/* allows every admin request
+type KubernetesAuthenticator struct {
+	Client kubernetes.Interface
+}
+
+// Authenticate implements Authenticator
+func (a KubernetesAuthenticator) Authenticate(req *http.Request) (string, error) {
+	token, ok := bearerToken(req)
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+
+	ctx := req.Context()
+	review, err := a.Client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "cannot review the token")
+	}
+	if !review.Status.Authenticated {
+		return "", fmt.Errorf("%w: %s", ErrUnauthenticated, review.Status.Error)
+	}
+
+	user := review.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	access, err := a.Client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: req.URL.Path,
+				Verb: strings.ToLower(req.Method),
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return user.Username, errors.Wrap(err, "cannot review the access")
+	}
+	if !access.Status.Allowed {
+		return user.Username, fmt.Errorf("%w: %s %s %s", ErrForbidden, user.Username, req.Method, req.URL.Path)
+	}
+	return user.Username, nil
+}
+
+func bearerToken(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+type userKey struct{}
+
+// User returns who sent an authenticated request
+func User(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// RequireAuth serves the requests the authenticator accepts, answering 401 to unauthenticated
+// and 403 to forbidden requests
+func RequireAuth(auth Authenticator, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		user, err := auth.Authenticate(req)
+		switch {
+		case err == nil:
+			handler.ServeHTTP(res, req.WithContext(context.WithValue(req.Context(), userKey{}, user)))
+			return
+		case errors.Is(err, ErrUnauthenticated):
+			res.Header().Set("WWW-Authenticate", `Bearer realm="node-refiner"`)
+			writeText(res, http.StatusUnauthorized, "unauthenticated")
+		case errors.Is(err, ErrForbidden):
+			writeText(res, http.StatusForbidden, "forbidden")
+		default:
+			writeText(res, http.StatusInternalServerError, "cannot authenticate the request")
+		}
+		zap.S().Warnw("Refused a request", "path", req.URL.Path, "method", req.Method, "user", user, "error", err)
+	})
+}
@@ -0,0 +1,63 @@
+package supervisor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func authRequest(h http.Handler, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/pause", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return res
+}
+
+// TestRequireAuth tests that only the requests the authenticator accepts are served, with the user in their context
+func TestRequireAuth(t *testing.T) {
+	var user string
+	served := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) { user = User(req.Context()) })
+
+	h := RequireAuth(TokenAuthenticator{Token: "secret"}, served)
+	for token, code := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "secret": http.StatusOK} {
+		if res := authRequest(h, token); res.Code != code {
+			t.Errorf("Expected %d with the token %q, got %d", code, token, res.Code)
+		}
+	}
+	if user != "token" {
+		t.Errorf("Expected the token user, got %q", user)
+	}
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		review.Status.Authenticated = review.Spec.Token != "invalid"
+		review.Status.User = authenticationv1.UserInfo{Username: review.Spec.Token, Groups: []string{"system:authenticated"}}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.NonResourceAttributes
+		review.Status.Allowed = review.Spec.User == "admin" && attributes.Path == "/api/v1/admin/pause" && attributes.Verb == "post"
+		return true, review, nil
+	})
+
+	h = RequireAuth(KubernetesAuthenticator{Client: client}, served)
+	for token, code := range map[string]int{"invalid": http.StatusUnauthorized, "viewer": http.StatusForbidden, "admin": http.StatusOK} {
+		if res := authRequest(h, token); res.Code != code {
+			t.Errorf("Expected %d for the user %q, got %d", code, token, res.Code)
+		}
+	}
+	if user != "admin" {
+		t.Errorf("Expected the admin user, got %q", user)
+	}
+}
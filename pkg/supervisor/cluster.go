@@ -11,43 +11,64 @@ type ClusterMetrics struct {
 	ExcessNodes             prometheus.Gauge
 	NumberOfNonTaintedNodes prometheus.Gauge
 	NumberOfNodes           prometheus.Gauge
+	NotReadyNodes           prometheus.Gauge
 	NumberOfPods            prometheus.Gauge
+	PendingPods             prometheus.Gauge
 	UnschedulableNodes      prometheus.Gauge
 	CPUUtilization          prometheus.Gauge
 	RAMUtilization          prometheus.Gauge
+	CostPerHour             prometheus.Gauge
+	ExcessCostPerHour       prometheus.Gauge
 }
 
-// InitClusterMetrics initializes these metrics
-func InitClusterMetrics(prefix string) *ClusterMetrics {
+// InitClusterMetrics initializes these metrics and registers them to the registerer
+func InitClusterMetrics(prefix string, registerer prometheus.Registerer) *ClusterMetrics {
+	factory := promauto.With(registerer)
 	cm := ClusterMetrics{
-		ExcessNodes: promauto.NewGauge(prometheus.GaugeOpts{
+		ExcessNodes: factory.NewGauge(prometheus.GaugeOpts{
 			Name: prefix + "_cluster_excess_nodes",
 			Help: "Number of excess nodes in the cluster",
 		}),
-		NumberOfNonTaintedNodes: promauto.NewGauge(prometheus.GaugeOpts{
+		NumberOfNonTaintedNodes: factory.NewGauge(prometheus.GaugeOpts{
 			Name: prefix + "_cluster_non_tainted_nodes",
 			Help: "Total number of non tainted nodes in the cluster",
 		}),
-		NumberOfNodes: promauto.NewGauge(prometheus.GaugeOpts{
+		NumberOfNodes: factory.NewGauge(prometheus.GaugeOpts{
 			Name: prefix + "_cluster_nodes",
 			Help: "Total number of nodes in the cluster",
 		}),
-		NumberOfPods: promauto.NewGauge(prometheus.GaugeOpts{
+		NotReadyNodes: factory.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "_cluster_not_ready_nodes",
+			Help: "Number of nodes that are not ready",
+		}),
+		NumberOfPods: factory.NewGauge(prometheus.GaugeOpts{
 			Name: prefix + "_cluster_pods",
 			Help: "Total number of pods in the cluster",
 		}),
-		CPUUtilization: promauto.NewGauge(prometheus.GaugeOpts{
+		PendingPods: factory.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "_cluster_pending_pods",
+			Help: "Number of pending pods the scheduler found no node for",
+		}),
+		CPUUtilization: factory.NewGauge(prometheus.GaugeOpts{
 			Name: prefix + "_cluster_cpu_utilization",
 			Help: "Overall utilization of CPU resources in the cluster",
 		}),
-		RAMUtilization: promauto.NewGauge(prometheus.GaugeOpts{
+		RAMUtilization: factory.NewGauge(prometheus.GaugeOpts{
 			Name: prefix + "_cluster_memory_utilization",
 			Help: "Overall utilization of memory resources in the cluster",
 		}),
-		UnschedulableNodes: promauto.NewGauge(prometheus.GaugeOpts{
+		UnschedulableNodes: factory.NewGauge(prometheus.GaugeOpts{
 			Name: prefix + "_cluster_unschedulable_nodes",
 			Help: "Number of nodes that are unschedulable",
 		}),
+		CostPerHour: factory.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "_cost_per_hour",
+			Help: "Estimated hourly cost of the nodes of the cluster, from the price table",
+		}),
+		ExcessCostPerHour: factory.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "_excess_cost_per_hour",
+			Help: "Estimated hourly cost of the excess nodes of the cluster, from the price table",
+		}),
 	}
 	return &cm
 }
@@ -57,9 +78,15 @@ func (cm *ClusterMetrics) PublishClusterMetrics(clusterState *types.ClusterManif
 	cm.ExcessNodes.Set(clusterState.ExcessNodes)
 	cm.NumberOfNonTaintedNodes.Set(float64(clusterState.NumberOfNonTaintedNodes))
 	cm.NumberOfNodes.Set(float64(clusterState.NumberOfNodes))
+	cm.NotReadyNodes.Set(float64(clusterState.NumberOfNotReadyNodes))
 	cm.NumberOfPods.Set(float64(clusterState.NumberOfPods))
+	cm.PendingPods.Set(float64(clusterState.NumberOfPendingPods))
 	cm.CPUUtilization.Set(clusterState.Utilization.PercentageCPU)
 	cm.RAMUtilization.Set(clusterState.Utilization.PercentageRAM)
+	if clusterState.Priced {
+		cm.CostPerHour.Set(clusterState.CostPerHour)
+		cm.ExcessCostPerHour.Set(clusterState.ExcessCostPerHour)
+	}
 }
 
 // PublishNodeUnschedulable updates the number of unschedulable nodes
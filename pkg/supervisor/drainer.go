@@ -8,26 +8,73 @@ import (
 // DrainerMetrics is struct of prometheus metrics to be exported
 type DrainerMetrics struct {
 	// Drainer Metrics
-	NodesCordoned   prometheus.Counter
-	NodesDrained    prometheus.Counter
-	NodesUncordoned prometheus.Counter
+	NodesCordoned    prometheus.Counter
+	NodesDrained     prometheus.Counter
+	NodesUncordoned  prometheus.Counter
+	PodsDeleted      prometheus.Counter
+	ScaleDownsFailed prometheus.Counter
+	NextDrainWindow  prometheus.Gauge
+	Paused           prometheus.Gauge
 }
 
-// InitDrainerMetrics initializes these metrics
-func InitDrainerMetrics(prefix string) *DrainerMetrics {
+// InitDrainerMetrics initializes these metrics and registers them to the registerer
+func InitDrainerMetrics(prefix string, registerer prometheus.Registerer) *DrainerMetrics {
+	factory := promauto.With(registerer)
 	dm := DrainerMetrics{
-		NodesCordoned: promauto.NewCounter(prometheus.CounterOpts{
+		NodesCordoned: factory.NewCounter(prometheus.CounterOpts{
 			Name: prefix + "_nodes_cordoned",
 			Help: "Number of nodes that were cordoned by node refiner",
 		}),
-		NodesDrained: promauto.NewCounter(prometheus.CounterOpts{
+		NodesDrained: factory.NewCounter(prometheus.CounterOpts{
 			Name: prefix + "_nodes_drained",
 			Help: "Number of nodes that were drained by node refiner",
 		}),
-		NodesUncordoned: promauto.NewCounter(prometheus.CounterOpts{
+		NodesUncordoned: factory.NewCounter(prometheus.CounterOpts{
 			Name: prefix + "_nodes_uncordoned",
 			Help: "Number of nodes that were uncordoned by node refiner",
 		}),
+		PodsDeleted: factory.NewCounter(prometheus.CounterOpts{
+			Name: prefix + "_pods_deleted",
+			Help: "Number of pods that were deleted instead of evicted by node refiner",
+		}),
+		ScaleDownsFailed: factory.NewCounter(prometheus.CounterOpts{
+			Name: prefix + "_scale_downs_failed",
+			Help: "Number of scale downs that failed and were reverted by node refiner",
+		}),
+		NextDrainWindow: factory.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "_next_drain_window_timestamp_seconds",
+			Help: "Next time the drain schedule allows drains, as a Unix timestamp, 0 if it never does",
+		}),
+		Paused: factory.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "_drainer_paused",
+			Help: "Whether an administrator paused the drainer, 1 if paused",
+		}),
 	}
 	return &dm
 }
+
+// savingsCollector exports the cost saved by the scale downs, calculated when scraped
+type savingsCollector struct {
+	desc   *prometheus.Desc
+	source func() float64
+}
+
+// RegisterSavings exports the cost saved by the scale downs, as returned by source. The savings
+// are not labelled by node, drained nodes are removed and replaced all the time
+func (s *Supervisor) RegisterSavings(source func() float64) {
+	s.Registry.MustRegister(&savingsCollector{
+		desc: prometheus.NewDesc(s.Prefix+"_estimated_savings_total",
+			"Estimated cost saved since the start by the nodes drained by node refiner, from the price table", nil, nil),
+		source: source,
+	})
+}
+
+// Describe implements prometheus.Collector
+func (c *savingsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *savingsCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, c.source())
+}
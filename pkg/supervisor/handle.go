@@ -1,9 +1,11 @@
 package supervisor
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
+	"sort"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -14,52 +16,52 @@ const (
 	HeartBeatGraceSeconds int64 = 180
 )
 
-var (
-	// Heartbeat global heartbeat variable
-	Heartbeat = time.Now()
-	// Healthy global health variable
-	Healthy = true
-)
-
-// Handler implements a HTTP response handler that reports on the current
-// liveness status of the controller
-type Handler struct {
-	MaxLoopTime time.Duration
+// LivenessHandler serves the liveness of the controller, /healthz
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if err := h.Live(); err != nil {
+			zap.S().Errorw("liveness failed", "error", err)
+			writeText(res, http.StatusServiceUnavailable, "service unhealthy: "+err.Error())
+			return
+		}
+		writeText(res, http.StatusOK, "OK")
+	})
 }
 
-func UpdateHeartbeat() {
-	Heartbeat = time.Now()
-}
+// ReadinessHandler serves the readiness of the controller, /readyz, listing the failing components
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		status := h.Status()
+		if status.Ready {
+			writeText(res, http.StatusOK, "OK")
+			return
+		}
 
-func (h *Handler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	if !Healthy || Heartbeat.Add(h.MaxLoopTime).Before(time.Now()) {
-		zap.S().Errorw("liveness failed", "healthy", Healthy, "heartbeat", Heartbeat, "maxLoopTime", h.MaxLoopTime)
-		res.WriteHeader(http.StatusServiceUnavailable)
-		_, err := res.Write(errMsg(Heartbeat, h.MaxLoopTime))
-		Check(err)
-	}
-	_, err := res.Write([]byte("OK"))
-	Check(err)
+		var failing []string
+		for name, state := range status.Components {
+			if !state.Healthy {
+				failing = append(failing, fmt.Sprintf("%s: %s", name, state.Message))
+			}
+		}
+		sort.Strings(failing)
+		writeText(res, http.StatusServiceUnavailable, "service not ready: "+strings.Join(failing, ", "))
+	})
 }
 
-func errMsg(hearbeat time.Time, maxLoopTime time.Duration) []byte {
-	return []byte(fmt.Sprintf(
-		"service unhealthy failed. Last heartbeat time: %s. Resync period: %s. Current time: %s",
-		hearbeat.Format(time.RFC3339),
-		maxLoopTime,
-		time.Now().Format(time.RFC3339),
-	))
+// StatusHandler serves the detailed state of the controller as JSON, /statusz
+func (h *Health) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(res).Encode(h.Status()); err != nil {
+			zap.S().Warnw("unable to write status response", "error", err)
+		}
+	})
 }
 
-// Check examins an error. On nil it returns and on not-nil it logs the error and calls Fatal
-// (corresponding to os.Exit(1))
-func Check(e error) {
-	if e == nil {
-		return
+func writeText(res http.ResponseWriter, code int, text string) {
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	res.WriteHeader(code)
+	if _, err := res.Write([]byte(text)); err != nil {
+		zap.S().Warnw("unable to write health response", "error", err)
 	}
-
-	Healthy = false
-
-	zap.S().Fatalf("failed check", e)
-
 }
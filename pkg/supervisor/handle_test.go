@@ -0,0 +1,88 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func probe(h http.Handler, path string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+	return res
+}
+
+// TestLivenessHeartbeat tests that liveness fails once the heartbeat is older than the liveness threshold
+func TestLivenessHeartbeat(t *testing.T) {
+	fc := clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+	h := NewHealth(fc, time.Minute)
+	h.Beat(fc.Now(), time.Second)
+
+	fc.Step(LivenessThreshold(time.Minute))
+	if res := probe(h.LivenessHandler(), "/healthz"); res.Code != http.StatusOK || res.Body.String() != "OK" {
+		t.Errorf("Expected %d OK within the liveness threshold, got %d %s", http.StatusOK, res.Code, res.Body)
+	}
+	fc.Step(time.Second)
+	res := probe(h.LivenessHandler(), "/healthz")
+	if res.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d after the liveness threshold, got %d", http.StatusServiceUnavailable, res.Code)
+	}
+	if strings.Contains(res.Body.String(), "OK") {
+		t.Errorf("Expected only the failure in the response, got %s", res.Body)
+	}
+
+	// A server failure makes the controller unready until the server is restarted, not unlive
+	h.Beat(fc.Now(), time.Second)
+	h.SetComponent(ComponentServer, errors.New("port in use"))
+	if res := probe(h.LivenessHandler(), "/healthz"); res.Code != http.StatusOK {
+		t.Errorf("Expected %d after a server failure, got %d", http.StatusOK, res.Code)
+	}
+	if res := probe(h.ReadinessHandler(), "/readyz"); res.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d after a server failure, got %d", http.StatusServiceUnavailable, res.Code)
+	}
+	h.SetComponent(ComponentServer, nil)
+	if res := probe(h.ReadinessHandler(), "/readyz"); res.Code != http.StatusOK {
+		t.Errorf("Expected %d once the server is restarted, got %d", http.StatusOK, res.Code)
+	}
+}
+
+// TestReadiness tests that the controller is only ready once every component and check is healthy
+func TestReadiness(t *testing.T) {
+	fc := clocktesting.NewFakeClock(time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC))
+	h := NewHealth(fc, time.Minute)
+	h.SetComponent("informers", ErrNotReported)
+	apiErr := errors.New("connection refused")
+	h.AddReadinessCheck("api", func() error { return apiErr })
+	h.SetDrainerPhase(func() string { return "idle" })
+
+	res := probe(h.ReadinessHandler(), "/readyz")
+	if res.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected %d before the informers synced, got %d", http.StatusServiceUnavailable, res.Code)
+	}
+	if want := "api: connection refused, informers: not reported yet"; !strings.Contains(res.Body.String(), want) {
+		t.Errorf("Expected the failing components %q, got %s", want, res.Body)
+	}
+
+	h.SetComponent("informers", nil)
+	apiErr = nil
+	if res := probe(h.ReadinessHandler(), "/readyz"); res.Code != http.StatusOK {
+		t.Fatalf("Expected %d once every component is healthy, got %d %s", http.StatusOK, res.Code, res.Body)
+	}
+
+	var status Status
+	if err := json.NewDecoder(probe(h.StatusHandler(), "/statusz").Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Live || !status.Ready || status.DrainerPhase != "idle" || len(status.Components) != 2 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if status.LivenessThreshold != LivenessThreshold(time.Minute).String() {
+		t.Errorf("Expected the liveness threshold %v, got %s", LivenessThreshold(time.Minute), status.LivenessThreshold)
+	}
+}
@@ -0,0 +1,164 @@
+package supervisor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/utils/clock"
+)
+
+// ErrNotReported is the state of a component that didn't report yet
+var ErrNotReported = errors.New("not reported yet")
+
+// ComponentServer is the component of the server of the metrics and health endpoints, unhealthy
+// from a failure until it is restarted
+const ComponentServer = "server"
+
+// ComponentState is the last state a component of the controller reported
+type ComponentState struct {
+	Healthy bool      `json:"healthy"`
+	Message string    `json:"message,omitempty"`
+	Since   time.Time `json:"since"`
+}
+
+// Status is the detailed state of the controller served on /statusz
+type Status struct {
+	Live              bool                      `json:"live"`
+	Ready             bool                      `json:"ready"`
+	Heartbeat         time.Time                 `json:"heartbeat"`
+	LivenessThreshold string                    `json:"livenessThreshold"`
+	LastLoopDuration  string                    `json:"lastLoopDuration"`
+	DrainerPhase      string                    `json:"drainerPhase,omitempty"`
+	Components        map[string]ComponentState `json:"components"`
+}
+
+// Health tracks the liveness and readiness of the controller. The controller is live while
+// its calculation loop beats within the liveness threshold, it is ready when every readiness
+// check passes and every component reported healthy. Failures of restartable components, like
+// the server, only affect the readiness
+type Health struct {
+	clock             clock.PassiveClock
+	livenessThreshold time.Duration
+
+	mu           sync.RWMutex
+	heartbeat    time.Time
+	loopDuration time.Duration
+	components   map[string]ComponentState
+	checks       map[string]func() error
+	drainerPhase func() string
+}
+
+// LivenessThreshold is the age of the last heartbeat after which the controller is not live
+// anymore, a loop interval plus a grace period
+func LivenessThreshold(loopInterval time.Duration) time.Duration {
+	return loopInterval + time.Duration(HeartBeatGraceSeconds)*time.Second
+}
+
+// NewHealth returns the health of a controller running its calculation loop every loop
+// interval, the real clock is used when clock is nil
+func NewHealth(c clock.PassiveClock, loopInterval time.Duration) *Health {
+	if c == nil {
+		c = clock.RealClock{}
+	}
+	return &Health{
+		clock:             c,
+		livenessThreshold: LivenessThreshold(loopInterval),
+		heartbeat:         c.Now(),
+		components:        map[string]ComponentState{},
+		checks:            map[string]func() error{},
+	}
+}
+
+// Beat records that the calculation loop ran at the given time and how long it took
+func (h *Health) Beat(at time.Time, loopDuration time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.heartbeat = at
+	h.loopDuration = loopDuration
+}
+
+// Heartbeat returns the time the calculation loop last ran
+func (h *Health) Heartbeat() time.Time {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.heartbeat
+}
+
+// SetComponent records the state of a component, healthy when err is nil
+func (h *Health) SetComponent(name string, err error) {
+	state := ComponentState{Healthy: err == nil, Since: h.clock.Now()}
+	if err != nil {
+		state.Message = err.Error()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if previous, ok := h.components[name]; ok && previous.Healthy == state.Healthy && previous.Message == state.Message {
+		return
+	}
+	h.components[name] = state
+}
+
+// AddReadinessCheck registers a check run whenever the readiness is probed
+func (h *Health) AddReadinessCheck(name string, check func() error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// SetDrainerPhase registers the function reporting what the drainer is doing
+func (h *Health) SetDrainerPhase(phase func() string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drainerPhase = phase
+}
+
+// Live returns why the controller is not live, nil if it is
+func (h *Health) Live() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if now := h.clock.Now(); h.heartbeat.Add(h.livenessThreshold).Before(now) {
+		return errors.Errorf("last heartbeat at %s is older than %s, current time %s",
+			h.heartbeat.Format(time.RFC3339), h.livenessThreshold, now.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Status runs the readiness checks and returns the state of the controller
+func (h *Health) Status() Status {
+	h.mu.RLock()
+	checks := make(map[string]func() error, len(h.checks))
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	drainerPhase := h.drainerPhase
+	h.mu.RUnlock()
+
+	// The checks may be slow, they run without holding the lock
+	for name, check := range checks {
+		h.SetComponent(name, check())
+	}
+	phase := ""
+	if drainerPhase != nil {
+		phase = drainerPhase()
+	}
+
+	live := h.Live()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	status := Status{
+		Live:              live == nil,
+		Ready:             true,
+		Heartbeat:         h.heartbeat,
+		LivenessThreshold: h.livenessThreshold.String(),
+		LastLoopDuration:  h.loopDuration.String(),
+		DrainerPhase:      phase,
+		Components:        make(map[string]ComponentState, len(h.components)),
+	}
+	for name, state := range h.components {
+		status.Components[name] = state
+		status.Ready = status.Ready && state.Healthy
+	}
+	return status
+}
@@ -3,61 +3,166 @@
 package supervisor
 
 import (
-	"fmt"
+	"context"
+	"net"
 	"net/http"
-	"os"
 	"time"
 
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
-// Supervisor is an object to manage the exported prometheus metrics
+// Default settings of the supervisor server
+const (
+	DefaultListenAddress   = ":8080"
+	DefaultReadTimeout     = 10 * time.Second
+	DefaultWriteTimeout    = 30 * time.Second
+	DefaultIdleTimeout     = 2 * time.Minute
+	DefaultShutdownTimeout = 5 * time.Second
+)
+
+// ServerOptions configures the HTTP server serving the metrics and the health endpoints
+type ServerOptions struct {
+	// Address to listen on, host:port
+	Address string
+
+	// TLS is served when the certificate and key files are set
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// ReadTimeout also bounds reading the request headers
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is the time requests in flight get to complete once the server stops
+	ShutdownTimeout time.Duration
+}
+
+// DefaultServerOptions returns the options the server runs with when nothing is configured
+func DefaultServerOptions() ServerOptions {
+	return ServerOptions{
+		Address:         DefaultListenAddress,
+		ReadTimeout:     DefaultReadTimeout,
+		WriteTimeout:    DefaultWriteTimeout,
+		IdleTimeout:     DefaultIdleTimeout,
+		ShutdownTimeout: DefaultShutdownTimeout,
+	}
+}
+
+// Supervisor is an object to manage the exported prometheus metrics and the health endpoints,
+// served on a single address
 type Supervisor struct {
 	Prefix string
+	Server ServerOptions
+
+	// Registry the metrics of the supervisor are registered to
+	Registry *prometheus.Registry
 
 	DrainerMetrics *DrainerMetrics
 	ClusterMetrics *ClusterMetrics
+	Health         *Health
+
+	mux *http.ServeMux
 }
 
-// InitSupervisor initializes the supervisor using the two contexts, drainer metrics and cluster metrics
-func InitSupervisor(prefix string) *Supervisor {
+// InitSupervisor initializes the supervisor using the two contexts, drainer metrics and cluster metrics,
+// registered to a registry of its own, serving the given health
+func InitSupervisor(prefix string, health *Health, server ServerOptions) *Supervisor {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
 	s := Supervisor{
 		Prefix:         prefix,
-		DrainerMetrics: InitDrainerMetrics(prefix),
-		ClusterMetrics: InitClusterMetrics(prefix),
+		Server:         server,
+		Registry:       registry,
+		DrainerMetrics: InitDrainerMetrics(prefix, registry),
+		ClusterMetrics: InitClusterMetrics(prefix, registry),
+		Health:         health,
+		mux:            http.NewServeMux(),
 	}
+
+	s.mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	s.mux.Handle("/healthz", health.LivenessHandler())
+	// Alias of /healthz for the deployments probing the former liveness server
+	s.mux.Handle("/alive", health.LivenessHandler())
+	s.mux.Handle("/readyz", health.ReadinessHandler())
+	s.mux.Handle("/statusz", health.StatusHandler())
 	return &s
 }
 
-// StartSupervising opens a web port that can be used by prometheus to track the metrics we are exposing
-func (s *Supervisor) StartSupervising() {
-	go s.startLiveness()
-	go s.startPrometheus()
+// Handler returns the handler of every endpoint of the supervisor
+func (s *Supervisor) Handler() http.Handler {
+	return s.mux
 }
 
-func (s *Supervisor) startLiveness() {
-	livenessPort := "9102"
-	health := Handler{MaxLoopTime: 60 * time.Second}
-	zap.S().Infof("starting liveness monitor at %s", livenessPort)
-	http.Handle("/alive", &health)
-	Check(http.ListenAndServe(fmt.Sprintf(":%s", livenessPort), nil))
+// Handle registers a handler for the pattern next to the metrics and health endpoints
+func (s *Supervisor) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
 }
 
-// Start a Prometheus endpoint to listen to the metrics
-// node refiner is populating
-func (s *Supervisor) startPrometheus() {
-	// Setup Prometheus Metrics
-
-	port := os.Getenv("LISTENING_PORT")
+// Serve listens on the server address and serves the supervisor endpoints until stopCh is
+// closed, returning the reason it stopped
+func (s *Supervisor) Serve(stopCh <-chan struct{}) error {
+	if (s.Server.TLSCertFile == "") != (s.Server.TLSKeyFile == "") {
+		return errors.New("serving TLS requires both a certificate and a key file")
+	}
+	l, err := net.Listen("tcp", s.Server.Address)
+	if err != nil {
+		err = errors.Wrapf(err, "cannot listen on %s", s.Server.Address)
+		s.Health.SetComponent(ComponentServer, err)
+		return err
+	}
+	s.Health.SetComponent(ComponentServer, nil)
+	err = s.serve(l, stopCh)
+	if err != nil {
+		s.Health.SetComponent(ComponentServer, err)
+	}
+	return err
+}
 
-	if port == "" {
-		port = "8080"
+func (s *Supervisor) serve(l net.Listener, stopCh <-chan struct{}) error {
+	server := &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: s.Server.ReadTimeout,
+		ReadTimeout:       s.Server.ReadTimeout,
+		WriteTimeout:      s.Server.WriteTimeout,
+		IdleTimeout:       s.Server.IdleTimeout,
 	}
 
-	http.Handle("/metrics", promhttp.Handler())
-	zap.S().Infow("Started serving metrics on /metrics")
-	zap.S().Infow("listening on", "port", port)
-	zap.S().Fatal(http.ListenAndServe(":"+port, nil))
+	// The server is shut down when stopCh is closed, done is closed when it failed on its own
+	stopped := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(stopped)
+		select {
+		case <-stopCh:
+		case <-done:
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), s.Server.ShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			zap.S().Warnw("Unable to shut the server down gracefully", "error", err)
+		}
+	}()
 
+	var err error
+	if s.Server.TLSCertFile != "" {
+		zap.S().Infow("Serving metrics and health endpoints over TLS", "address", l.Addr().String())
+		err = server.ServeTLS(l, s.Server.TLSCertFile, s.Server.TLSKeyFile)
+	} else {
+		zap.S().Infow("Serving metrics and health endpoints", "address", l.Addr().String())
+		err = server.Serve(l)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		<-stopped
+		return nil
+	}
+	return err
 }
@@ -0,0 +1,84 @@
+package supervisor
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSupervisorHandler tests that a single handler serves the metrics of the supervisor and the health
+// endpoints, and that supervisors don't share their registries
+func TestSupervisorHandler(t *testing.T) {
+	s := InitSupervisor("test", NewHealth(nil, time.Minute), DefaultServerOptions())
+	// A second supervisor registers the same metrics to a registry of its own
+	InitSupervisor("test", NewHealth(nil, time.Minute), DefaultServerOptions())
+	s.ClusterMetrics.NumberOfNodes.Set(3)
+
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	for path, want := range map[string]string{
+		"/metrics": "test_cluster_nodes 3",
+		"/healthz": "OK",
+		"/alive":   "OK",
+		"/readyz":  "OK",
+		"/statusz": `"live":true`,
+	} {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("Expected %s to serve %d with %q, got %d %s", path, http.StatusOK, want, res.StatusCode, body)
+		}
+	}
+}
+
+// TestSupervisorServe tests that the server stops without an error once the stop channel is closed
+func TestSupervisorServe(t *testing.T) {
+	s := InitSupervisor("test", NewHealth(nil, time.Minute), DefaultServerOptions())
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopCh := make(chan struct{})
+	served := make(chan error)
+	go func() { served <- s.serve(l, stopCh) }()
+
+	res, err := http.Get("http://" + l.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected %d, got %d", http.StatusOK, res.StatusCode)
+	}
+
+	close(stopCh)
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected no error once stopped, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the server to stop")
+	}
+	if err := s.Health.Live(); err != nil {
+		t.Errorf("Expected the controller to stay live, got %v", err)
+	}
+
+	s.Server.TLSCertFile = "tls.crt"
+	if err := s.Serve(make(chan struct{})); err == nil {
+		t.Error("Expected an error serving TLS without a key file")
+	}
+}
@@ -0,0 +1,101 @@
+// Package tracing traces the calculation loop and the scale downs with OpenTelemetry, exported
+// over OTLP. Spans are no-ops until Setup installs a tracer provider
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// TracerName is the name of the tracer of node refiner
+const TracerName = "github.com/SAP/node-refiner"
+
+// DefaultServiceName is the service the spans are reported for
+const DefaultServiceName = "node-refiner"
+
+// Options configures the export of the spans, tracing is disabled when no endpoint is set
+type Options struct {
+	// Endpoint of the OTLP/HTTP collector, as host:port
+	Endpoint string
+	// Insecure sends the spans over HTTP instead of HTTPS
+	Insecure bool
+	// SampleRatio is the fraction of the traces sampled, from 0 to 1
+	SampleRatio float64
+	// ServiceName the spans are reported for, DefaultServiceName when empty
+	ServiceName string
+}
+
+// Enabled reports whether the spans are exported
+func (o Options) Enabled() bool {
+	return o.Endpoint != ""
+}
+
+// Setup installs the global tracer provider exporting the spans to the collector. The returned
+// function flushes the spans left and stops the export
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	if !opts.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return nil, errors.Errorf("invalid trace sample ratio %v, expected a value from 0 to 1", opts.SampleRatio)
+	}
+	serviceName := opts.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+
+	clientOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint), otlptracehttp.WithTimeout(10 * time.Second)}
+	if opts.Insecure {
+		clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create the OTLP exporter")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	zap.S().Infow("Exporting traces", "endpoint", opts.Endpoint, "sampleRatio", opts.SampleRatio)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span child of the span in ctx, if any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends the span, marking it failed with err if not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Logger returns the logger with the IDs of the trace and the span in ctx, so that the logs can
+// be correlated with the spans
+func Logger(ctx context.Context) *zap.SugaredLogger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return zap.S()
+	}
+	return zap.S().With("traceID", sc.TraceID().String(), "spanID", sc.SpanID().String())
+}
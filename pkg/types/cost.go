@@ -0,0 +1,126 @@
+package types
+
+import (
+	"io/ioutil"
+	"strconv"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultPriceAnnotation is the node annotation holding the hourly price of the node, it takes
+// precedence over the price table
+const DefaultPriceAnnotation = "node-refiner.sap.com/price-per-hour"
+
+// PoolLabels are the node labels the pool of a node is read from, by order of precedence
+var PoolLabels = []string{
+	"worker.gardener.cloud/pool",
+	"cloud.google.com/gke-nodepool",
+	"eks.amazonaws.com/nodegroup",
+	"kubernetes.azure.com/agentpool",
+	"agentpool",
+}
+
+// DefaultPriceLabels are the node labels the prices are looked up by, by order of precedence:
+// the instance type first, then the pool
+var DefaultPriceLabels = append([]string{v1.LabelInstanceTypeStable, v1.LabelInstanceType}, PoolLabels...)
+
+// PriceTable maps the nodes to their hourly price
+type PriceTable struct {
+	// Currency of the prices, only used in reports
+	Currency string `json:"currency,omitempty"`
+	// Annotation holding the price of a node, DefaultPriceAnnotation when empty
+	Annotation string `json:"annotation,omitempty"`
+	// Labels whose values the prices are keyed by, DefaultPriceLabels when empty
+	Labels []string `json:"labels,omitempty"`
+	// Prices per hour by instance type or pool
+	Prices map[string]float64 `json:"prices"`
+	// Default price of the nodes matching no price, such nodes are left out of the costs when unset
+	Default *float64 `json:"default,omitempty"`
+}
+
+// LoadPriceTable reads a price table from a YAML or JSON file
+func LoadPriceTable(path string) (*PriceTable, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read the price table")
+	}
+	pt := &PriceTable{}
+	if err := yaml.UnmarshalStrict(data, pt); err != nil {
+		return nil, errors.Wrapf(err, "invalid price table %s", path)
+	}
+	for key, price := range pt.Prices {
+		if price < 0 {
+			return nil, errors.Errorf("invalid price table %s: negative price %v for %s", path, price, key)
+		}
+	}
+	if pt.Default != nil && *pt.Default < 0 {
+		return nil, errors.Errorf("invalid price table %s: negative default price %v", path, *pt.Default)
+	}
+	return pt, nil
+}
+
+// NodePrice returns the hourly price of the node, false if it has none
+func (pt *PriceTable) NodePrice(node *v1.Node) (float64, bool) {
+	annotation := pt.Annotation
+	if annotation == "" {
+		annotation = DefaultPriceAnnotation
+	}
+	if value, ok := node.Annotations[annotation]; ok {
+		if price, err := strconv.ParseFloat(value, 64); err == nil && price >= 0 {
+			return price, true
+		}
+	}
+
+	labels := pt.Labels
+	if len(labels) == 0 {
+		labels = DefaultPriceLabels
+	}
+	for _, label := range labels {
+		if value, ok := node.Labels[label]; ok {
+			if price, ok := pt.Prices[value]; ok {
+				return price, true
+			}
+		}
+	}
+
+	if pt.Default != nil {
+		return *pt.Default, true
+	}
+	return 0, false
+}
+
+// NodePool returns the pool of the node, empty if unknown
+func NodePool(node *v1.Node) string {
+	for _, label := range PoolLabels {
+		if pool, ok := node.Labels[label]; ok {
+			return pool
+		}
+	}
+	return ""
+}
+
+// CalculateCost prices the nodes and the cluster. The excess nodes are priced like the sample
+// node they are measured in. Nothing is priced when pt is nil
+func (cm *ClusterManifest) CalculateCost(nodesMap map[string]NodeManifest, sampleNode *NodeManifest, pt *PriceTable) {
+	if pt == nil {
+		return
+	}
+	cm.Priced = true
+	cm.Currency = pt.Currency
+	cm.CostPerHour = 0
+	for key, nm := range nodesMap {
+		nm.CostPerHour, nm.Priced = pt.NodePrice(nm.Node)
+		nodesMap[key] = nm
+		cm.CostPerHour += nm.CostPerHour
+	}
+
+	cm.ExcessCostPerHour = 0
+	if sampleNode == nil || cm.ExcessNodes <= 0 {
+		return
+	}
+	if price, ok := pt.NodePrice(sampleNode.Node); ok {
+		cm.ExcessCostPerHour = cm.ExcessNodes * price
+	}
+}
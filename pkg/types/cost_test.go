@@ -0,0 +1,48 @@
+package types
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// TestCalculateCost tests that the nodes are priced by annotation, instance type, pool or default
+// and that the excess nodes are priced like the candidate
+func TestCalculateCost(t *testing.T) {
+	nodesMap := testNodesMap()
+	nodesMap["a"].Node.Labels = map[string]string{v1.LabelInstanceTypeStable: "m5.large", "worker.gardener.cloud/pool": "workers"}
+	nodesMap["b"].Node.Labels = map[string]string{"worker.gardener.cloud/pool": "workers"}
+	nodesMap["c"].Node.Annotations = map[string]string{DefaultPriceAnnotation: "0.5"}
+	defaultPrice := 0.1
+	pt := &PriceTable{Currency: "EUR", Prices: map[string]float64{"m5.large": 0.2, "workers": 0.3}, Default: &defaultPrice}
+
+	cm := ClusterManifest{ExcessNodes: 1.5}
+	sample := nodesMap["a"]
+	cm.CalculateCost(nodesMap, &sample, pt)
+	for name, price := range map[string]float64{"a": 0.2, "b": 0.3, "c": 0.5} {
+		if nm := nodesMap[name]; !nm.Priced || nm.CostPerHour != price {
+			t.Errorf("Expected %s to cost %v, got %+v", name, price, nm)
+		}
+	}
+	if cm.CostPerHour != 1 || cm.ExcessCostPerHour < 0.299 || cm.ExcessCostPerHour > 0.301 {
+		t.Errorf("Unexpected cluster cost %v and excess cost %v", cm.CostPerHour, cm.ExcessCostPerHour)
+	}
+
+	pt.Default = nil
+	delete(nodesMap["c"].Node.Annotations, DefaultPriceAnnotation)
+	cm.CalculateCost(nodesMap, &sample, pt)
+	if nodesMap["c"].Priced || cm.CostPerHour != 0.5 {
+		t.Errorf("Expected the node without a price to be left out, got %+v and %v", nodesMap["c"], cm.CostPerHour)
+	}
+
+	var buf bytes.Buffer
+	if err := TabulateCluster(&buf, OutputCSV, &cm); err != nil || !strings.Contains(buf.String(), "Cost per Hour,0.50 EUR") {
+		t.Errorf("Expected the cost in the report, got %v:\n%s", err, buf.String())
+	}
+	buf.Reset()
+	if err := TabulateNodeMap(&buf, OutputCSV, nodesMap, "a"); err != nil || !strings.HasSuffix(strings.Split(buf.String(), "\n")[1], ",0.20") {
+		t.Errorf("Expected the cost of the nodes, got %v:\n%s", err, buf.String())
+	}
+}
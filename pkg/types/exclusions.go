@@ -0,0 +1,155 @@
+package types
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// Exclusions selects the pods of system namespaces and workloads, like kube-system or a
+// monitoring stack, that are left out of the utilization or keep their nodes from being drained,
+// as well as the pods whose priority is too high to be evicted
+type Exclusions struct {
+	// AllowedNamespaces, when not empty, excludes the pods of every other namespace
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+	// DeniedNamespaces excludes the pods of these namespaces
+	DeniedNamespaces []string `json:"deniedNamespaces,omitempty"`
+	// PodSelectors excludes the pods matching any of the selectors
+	PodSelectors []metav1.LabelSelector `json:"podSelectors,omitempty"`
+
+	// ExcludeFromUtilization leaves the requests of the excluded pods out of the utilization
+	ExcludeFromUtilization bool `json:"excludeFromUtilization,omitempty"`
+	// BlockDrain keeps the nodes running excluded pods from being drained. DaemonSet and mirror
+	// pods, which run on every node, never block a drain
+	BlockDrain bool `json:"blockDrain,omitempty"`
+	// PriorityThreshold keeps the nodes running pods of at least this priority, like
+	// system-node-critical (2000001000), from being drained. DaemonSet and mirror pods aside,
+	// as well as pods whose preemption policy is Never: they don't claim the resources of
+	// lower priority pods, so they may wait for their turn to be scheduled again like them
+	PriorityThreshold *int32 `json:"priorityThreshold,omitempty"`
+
+	selectors []labels.Selector
+}
+
+// LoadExclusions reads the exclusions from a YAML or JSON file
+func LoadExclusions(path string) (*Exclusions, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read the exclusions")
+	}
+	e := &Exclusions{}
+	if err := yaml.UnmarshalStrict(data, e); err != nil {
+		return nil, errors.Wrapf(err, "invalid exclusions %s", path)
+	}
+	if err := e.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid exclusions %s", path)
+	}
+	return e, nil
+}
+
+// Validate parses the pod selectors, it must be called before exclusions not read by
+// LoadExclusions are used
+func (e *Exclusions) Validate() error {
+	e.selectors = make([]labels.Selector, 0, len(e.PodSelectors))
+	for i := range e.PodSelectors {
+		selector, err := metav1.LabelSelectorAsSelector(&e.PodSelectors[i])
+		if err != nil {
+			return errors.Wrapf(err, "invalid pod selector %d", i)
+		}
+		if selector.Empty() {
+			return errors.Errorf("pod selector %d is empty, it would select every pod", i)
+		}
+		e.selectors = append(e.selectors, selector)
+	}
+	return nil
+}
+
+// WithPriorityThreshold sets the priority threshold, creating the exclusions if nil. The
+// exclusions are returned as they are when threshold is nil
+func (e *Exclusions) WithPriorityThreshold(threshold *int32) *Exclusions {
+	if threshold == nil {
+		return e
+	}
+	if e == nil {
+		e = &Exclusions{}
+	}
+	e.PriorityThreshold = threshold
+	return e
+}
+
+// Excludes reports whether the pod is excluded, nil exclusions exclude no pod
+func (e *Exclusions) Excludes(pod *v1.Pod) bool {
+	if e == nil {
+		return false
+	}
+	if len(e.AllowedNamespaces) > 0 && !contains(e.AllowedNamespaces, pod.Namespace) {
+		return true
+	}
+	if contains(e.DeniedNamespaces, pod.Namespace) {
+		return true
+	}
+	podLabels := labels.Set(pod.Labels)
+	for _, selector := range e.selectors {
+		if selector.Matches(podLabels) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludesFromUtilization reports whether the requests of the pod are left out of the utilization
+func (e *Exclusions) ExcludesFromUtilization(pod *v1.Pod) bool {
+	return e != nil && e.ExcludeFromUtilization && e.Excludes(pod)
+}
+
+// DrainBlocker describes why the pod keeps its node from being drained, empty if it doesn't
+func (e *Exclusions) DrainBlocker(pod *v1.Pod) string {
+	if e == nil || isDaemonSetPod(pod) || isMirrorPod(pod) {
+		return ""
+	}
+	name := pod.Namespace + "/" + pod.Name
+	if priority := PodPriority(pod); e.PriorityThreshold != nil && priority >= *e.PriorityThreshold && preempts(pod) {
+		return fmt.Sprintf("the pod %s of priority %d, the threshold is %d", name, priority, *e.PriorityThreshold)
+	}
+	if e.BlockDrain && e.Excludes(pod) {
+		return "the excluded pod " + name
+	}
+	return ""
+}
+
+// PodPriority returns the priority resolved from the pod's priority class, 0 when unset
+func PodPriority(pod *v1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+// preempts reports whether the pod may preempt lower priority pods, which it does by default
+func preempts(pod *v1.Pod) bool {
+	return pod.Spec.PreemptionPolicy == nil || *pod.Spec.PreemptionPolicy != v1.PreemptNever
+}
+
+func isDaemonSetPod(pod *v1.Pod) bool {
+	ref := metav1.GetControllerOf(pod)
+	return ref != nil && ref.Kind == "DaemonSet"
+}
+
+func isMirrorPod(pod *v1.Pod) bool {
+	_, ok := pod.Annotations[v1.MirrorPodAnnotationKey]
+	return ok
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
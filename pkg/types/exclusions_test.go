@@ -0,0 +1,80 @@
+package types
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestExclusions tests that pods are excluded by namespace or label and that DaemonSet pods never block drains
+func TestExclusions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exclusions.yaml")
+	config := `
+deniedNamespaces: [kube-system]
+podSelectors:
+- matchExpressions:
+  - {key: app, operator: In, values: [prometheus, grafana]}
+blockDrain: true
+`
+	if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	e, err := LoadExclusions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pod := func(namespace, app string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: app, Labels: map[string]string{"app": app}}}
+	}
+	dns, grafana, web := pod("kube-system", "coredns"), pod("default", "grafana"), pod("default", "web")
+	proxy := pod("kube-system", "kube-proxy")
+	isController := true
+	proxy.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "kube-proxy", Controller: &isController}}
+
+	for p, blocks := range map[*v1.Pod]bool{dns: true, grafana: true, web: false, proxy: false} {
+		if (e.DrainBlocker(p) != "") != blocks {
+			t.Errorf("Expected %s/%s to block drains: %v", p.Namespace, p.Name, blocks)
+		}
+		if e.ExcludesFromUtilization(p) {
+			t.Errorf("Expected %s/%s to count in the utilization", p.Namespace, p.Name)
+		}
+	}
+
+	e = &Exclusions{AllowedNamespaces: []string{"default"}}
+	if !e.Excludes(dns) || e.Excludes(web) {
+		t.Errorf("Expected only the pods of the allowed namespaces to be kept")
+	}
+	threshold, priority := int32(1000), int32(1000)
+	e = &Exclusions{PriorityThreshold: &threshold}
+	web.Spec.Priority = &priority
+	if e.DrainBlocker(web) == "" || e.DrainBlocker(proxy) != "" || e.DrainBlocker(grafana) != "" {
+		t.Errorf("Expected only the pods of at least the priority threshold to block drains")
+	}
+	never := v1.PreemptNever
+	web.Spec.PreemptionPolicy = &never
+	if e.DrainBlocker(web) != "" {
+		t.Errorf("Expected a pod that never preempts not to block drains")
+	}
+	var none *Exclusions
+	if none.Excludes(dns) || none.DrainBlocker(dns) != "" {
+		t.Errorf("Expected nil exclusions to exclude no pod")
+	}
+	web.Spec.PreemptionPolicy = nil
+	if none.WithPriorityThreshold(&threshold).DrainBlocker(web) == "" || none.WithPriorityThreshold(nil) != nil {
+		t.Errorf("Expected the priority threshold to apply without an exclusions file")
+	}
+
+	for _, invalid := range []string{"podSelectors: [{}]", "deniedNamespace: [kube-system]",
+		"podSelectors: [{matchExpressions: [{key: app, operator: Has}]}]"} {
+		if err := ioutil.WriteFile(path, []byte(invalid), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadExclusions(path); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
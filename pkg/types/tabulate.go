@@ -1,74 +1,399 @@
 package types
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
-	"os"
-
+	"io"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/SAP/node-refiner/pkg/common"
 	"github.com/jedib0t/go-pretty/table"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
 )
 
-// TabulateNodeMap Print the Nodes Metrics in a Table
-func TabulateNodeMap(nodesMap map[string]NodeManifest) {
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Node", "Tainted", "Pods", "CPU Pods Requests", "Memory Pods Requests", "CPU Allocatable", "Memory Allocatable", "% CPU", "% Memory", "Score"})
-
-	for nodeName, nodeManifest := range nodesMap {
-		t.AppendRow(table.Row{
-			nodeName,
-			func() string {
-				if common.CheckForTaints(nodeManifest.Node) {
-					return "yes"
-				}
-				return "no"
-			}(),
-			len(nodeManifest.Pods),
-			common.FormatValue("CPU", nodeManifest.TotalPodsRequests.ReqCPU), common.FormatValue("RAM", nodeManifest.TotalPodsRequests.ReqRAM),
-			common.FormatValue("CPU", nodeManifest.Metrics.AllocCPU), common.FormatValue("RAM", nodeManifest.Metrics.AllocRAM),
-			common.FormatPercentage(nodeManifest.Utilization.PercentageCPU), common.FormatPercentage(nodeManifest.Utilization.PercentageRAM),
-			fmt.Sprintf("%.2f", nodeManifest.Utilization.Score)})
-	}
-	t.Render()
-}
-
-// TabulatePodsMap Print pod analytics in a tabular form
-func TabulatePodsMap(podsMap map[string]PodManifest) {
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Pod", "Namespace", "Status", "CPU Requests", "Memory Requests"})
+// OutputFormat selects how the tabulate functions render their reports
+type OutputFormat string
+
+// Supported output formats
+const (
+	OutputTable    OutputFormat = "table"
+	OutputJSON     OutputFormat = "json"
+	OutputYAML     OutputFormat = "yaml"
+	OutputCSV      OutputFormat = "csv"
+	OutputMarkdown OutputFormat = "markdown"
+)
+
+// OutputFormats lists the supported output formats
+var OutputFormats = []OutputFormat{OutputTable, OutputJSON, OutputYAML, OutputCSV, OutputMarkdown}
+
+// ParseOutputFormat validates the name of an output format
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	for _, format := range OutputFormats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q, expected one of %v", name, OutputFormats)
+}
+
+// String implements fmt.Stringer and flag.Value
+func (f OutputFormat) String() string {
+	return string(f)
+}
+
+// Set implements flag.Value
+func (f *OutputFormat) Set(value string) error {
+	format, err := ParseOutputFormat(strings.ToLower(value))
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+// IsStructured returns whether the format is a serialization of the report records
+// rather than a table
+func (f OutputFormat) IsStructured() bool {
+	return f == OutputJSON || f == OutputYAML
+}
+
+// NodeReport is the flattened view of a NodeManifest used in reports
+type NodeReport struct {
+	Name                   string  `json:"name"`
+	Candidate              bool    `json:"candidate"`
+	Tainted                bool    `json:"tainted"`
+	Pods                   int     `json:"pods"`
+	CPURequestsMilli       int64   `json:"cpuRequestsMilli"`
+	MemoryRequestsBytes    int64   `json:"memoryRequestsBytes"`
+	CPUAllocatableMilli    int64   `json:"cpuAllocatableMilli"`
+	MemoryAllocatableBytes int64   `json:"memoryAllocatableBytes"`
+	PercentageCPU          float64 `json:"percentageCPU"`
+	PercentageRAM          float64 `json:"percentageRAM"`
+	Score                  float64 `json:"score"`
+	// CostPerHour is only set for the nodes with a price
+	CostPerHour *float64 `json:"costPerHour,omitempty"`
+}
+
+// PodReport is the flattened view of a PodManifest used in reports
+type PodReport struct {
+	Name                string `json:"name"`
+	Namespace           string `json:"namespace"`
+	Node                string `json:"node"`
+	Phase               string `json:"phase"`
+	CPURequestsMilli    int64  `json:"cpuRequestsMilli"`
+	MemoryRequestsBytes int64  `json:"memoryRequestsBytes"`
+}
+
+// ClusterReport is the flattened view of a ClusterManifest used in reports
+type ClusterReport struct {
+	Nodes                  int     `json:"nodes"`
+	NonTaintedNodes        int     `json:"nonTaintedNodes"`
+	NotReadyNodes          int     `json:"notReadyNodes"`
+	Pods                   int     `json:"pods"`
+	PendingPods            int     `json:"pendingPods"`
+	ExcessNodes            float64 `json:"excessNodes"`
+	CPURequestsMilli       int64   `json:"cpuRequestsMilli"`
+	MemoryRequestsBytes    int64   `json:"memoryRequestsBytes"`
+	CPUAllocatableMilli    int64   `json:"cpuAllocatableMilli"`
+	MemoryAllocatableBytes int64   `json:"memoryAllocatableBytes"`
+	PercentageCPU          float64 `json:"percentageCPU"`
+	PercentageRAM          float64 `json:"percentageRAM"`
+	// Costs are only set when a price table is
+	Currency          string   `json:"currency,omitempty"`
+	CostPerHour       *float64 `json:"costPerHour,omitempty"`
+	ExcessCostPerHour *float64 `json:"excessCostPerHour,omitempty"`
+}
+
+// NewNodeReports flattens the nodes sorted by ascending score, the drain candidate (if not empty) is marked
+func NewNodeReports(nodesMap map[string]NodeManifest, candidate string) []NodeReport {
+	reports := make([]NodeReport, 0, len(nodesMap))
+	for _, nodeName := range SortedNodeNames(nodesMap) {
+		nodeManifest := nodesMap[nodeName]
+		report := NodeReport{
+			Name:                   nodeName,
+			Candidate:              nodeName == candidate,
+			Tainted:                common.CheckForTaints(nodeManifest.Node),
+			Pods:                   len(nodeManifest.Pods),
+			CPURequestsMilli:       nodeManifest.TotalPodsRequests.ReqCPU.MilliValue(),
+			MemoryRequestsBytes:    nodeManifest.TotalPodsRequests.ReqRAM.Value(),
+			CPUAllocatableMilli:    nodeManifest.Metrics.AllocCPU.MilliValue(),
+			MemoryAllocatableBytes: nodeManifest.Metrics.AllocRAM.Value(),
+			PercentageCPU:          nodeManifest.Utilization.PercentageCPU,
+			PercentageRAM:          nodeManifest.Utilization.PercentageRAM,
+			Score:                  nodeManifest.Utilization.Score,
+		}
+		if nodeManifest.Priced {
+			cost := nodeManifest.CostPerHour
+			report.CostPerHour = &cost
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
 
-	for podName, podMetric := range podsMap {
-		t.AppendRow(table.Row{
-			podName,
-			podMetric.Pod.Namespace,
-			podMetric.Pod.Status.Phase,
-			common.FormatValue("CPU", podMetric.Metrics.ReqCPU), common.FormatValue("RAM", podMetric.Metrics.ReqRAM)})
+// NewPodReports flattens the pods sorted by namespace and name
+func NewPodReports(podsMap map[string]PodManifest) []PodReport {
+	reports := make([]PodReport, 0, len(podsMap))
+	for _, podManifest := range podsMap {
+		reports = append(reports, PodReport{
+			Name:                podManifest.Pod.Name,
+			Namespace:           podManifest.Pod.Namespace,
+			Node:                podManifest.Pod.Spec.NodeName,
+			Phase:               string(podManifest.Pod.Status.Phase),
+			CPURequestsMilli:    podManifest.Metrics.ReqCPU.MilliValue(),
+			MemoryRequestsBytes: podManifest.Metrics.ReqRAM.Value(),
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Namespace != reports[j].Namespace {
+			return reports[i].Namespace < reports[j].Namespace
+		}
+		return reports[i].Name < reports[j].Name
+	})
+	return reports
+}
+
+// NewClusterReport flattens the cluster manifest
+func NewClusterReport(clusterManifest *ClusterManifest) ClusterReport {
+	report := ClusterReport{
+		Nodes:                  clusterManifest.NumberOfNodes,
+		NonTaintedNodes:        clusterManifest.NumberOfNonTaintedNodes,
+		NotReadyNodes:          clusterManifest.NumberOfNotReadyNodes,
+		Pods:                   clusterManifest.NumberOfPods,
+		PendingPods:            clusterManifest.NumberOfPendingPods,
+		ExcessNodes:            clusterManifest.ExcessNodes,
+		CPURequestsMilli:       clusterManifest.TotalPodsMetrics.ReqCPU.MilliValue(),
+		MemoryRequestsBytes:    clusterManifest.TotalPodsMetrics.ReqRAM.Value(),
+		CPUAllocatableMilli:    clusterManifest.TotalNodeMetrics.AllocCPU.MilliValue(),
+		MemoryAllocatableBytes: clusterManifest.TotalNodeMetrics.AllocRAM.Value(),
+		PercentageCPU:          clusterManifest.Utilization.PercentageCPU,
+		PercentageRAM:          clusterManifest.Utilization.PercentageRAM,
+	}
+	if clusterManifest.Priced {
+		cost, excessCost := clusterManifest.CostPerHour, clusterManifest.ExcessCostPerHour
+		report.Currency = clusterManifest.Currency
+		report.CostPerHour = &cost
+		report.ExcessCostPerHour = &excessCost
 	}
-	t.Render()
+	return report
 }
 
-// TabulateCluster Print cluster analytics in a tabular form
-func TabulateCluster(clusterManifest *ClusterManifest) {
+// TabulateNodeMap writes the Nodes Metrics sorted by score in the given format, the drain
+// candidate (if not empty) is marked
+func TabulateNodeMap(w io.Writer, format OutputFormat, nodesMap map[string]NodeManifest, candidate string) error {
+	reports := NewNodeReports(nodesMap, candidate)
+	if format.IsStructured() {
+		return WriteStructured(w, format, reports)
+	}
+
+	priced := false
+	for _, r := range reports {
+		priced = priced || r.CostPerHour != nil
+	}
+	header := []string{"Candidate", "Node", "Tainted", "Pods", "CPU Pods Requests", "Memory Pods Requests", "CPU Allocatable", "Memory Allocatable", "% CPU", "% Memory", "Score"}
+	if priced {
+		header = append(header, "Cost/Hour")
+	}
+
+	rows := make([][]string, 0, len(reports))
+	for _, r := range reports {
+		row := []string{
+			candidateMarker(r.Candidate),
+			r.Name,
+			YesNo(r.Tainted),
+			strconv.Itoa(r.Pods),
+			formatMilliCPU(r.CPURequestsMilli), formatBytes(r.MemoryRequestsBytes),
+			formatMilliCPU(r.CPUAllocatableMilli), formatBytes(r.MemoryAllocatableBytes),
+			common.FormatPercentage(r.PercentageCPU), common.FormatPercentage(r.PercentageRAM),
+			fmt.Sprintf("%.2f", r.Score)}
+		if priced {
+			row = append(row, formatCost(r.CostPerHour))
+		}
+		rows = append(rows, row)
+	}
+	return WriteRows(w, format, "", header, rows)
+}
+
+// TabulatePodsMap writes the pod analytics sorted by namespace and name in the given format
+func TabulatePodsMap(w io.Writer, format OutputFormat, podsMap map[string]PodManifest) error {
+	reports := NewPodReports(podsMap)
+	if format.IsStructured() {
+		return WriteStructured(w, format, reports)
+	}
+
+	rows := make([][]string, 0, len(reports))
+	for _, r := range reports {
+		rows = append(rows, []string{
+			r.Name,
+			r.Namespace,
+			r.Phase,
+			formatMilliCPU(r.CPURequestsMilli), formatBytes(r.MemoryRequestsBytes)})
+	}
+	return WriteRows(w, format, "", []string{"Pod", "Namespace", "Status", "CPU Requests", "Memory Requests"}, rows)
+}
+
+// TabulateCluster writes the cluster analytics in the given format. The table format keeps the
+// counters in the title, csv and markdown flatten everything into metric/value rows
+func TabulateCluster(w io.Writer, format OutputFormat, clusterManifest *ClusterManifest) error {
+	r := NewClusterReport(clusterManifest)
+	switch {
+	case format.IsStructured():
+		return WriteStructured(w, format, r)
+	case format == OutputTable:
+		title := fmt.Sprintf("Cluster State\n"+
+			"Number of Nodes: %v\n"+
+			"Number of Pods: %v \n"+
+			"Number of unschedulable Pods: %v\n"+
+			"Number of non-tainted Nodes: %v\n"+
+			"Number of not ready Nodes: %v\n"+
+			"Excess Nodes: %.2f",
+			r.Nodes, r.Pods, r.PendingPods, r.NonTaintedNodes, r.NotReadyNodes, r.ExcessNodes)
+		if r.CostPerHour != nil {
+			title += fmt.Sprintf("\nCost per Hour: %s\nExcess Cost per Hour: %s",
+				FormatCurrency(*r.CostPerHour, r.Currency), FormatCurrency(*r.ExcessCostPerHour, r.Currency))
+		}
+		return WriteRows(w, format, title, []string{"Resource", "Pods Consumption", "Nodes Allocatable", "Percentage"}, [][]string{
+			{"CPU", formatMilliCPU(r.CPURequestsMilli), formatMilliCPU(r.CPUAllocatableMilli), common.FormatPercentage(r.PercentageCPU)},
+			{"RAM", formatBytes(r.MemoryRequestsBytes), formatBytes(r.MemoryAllocatableBytes), common.FormatPercentage(r.PercentageRAM)},
+		})
+	default:
+		rows := [][]string{
+			{"Number of Nodes", strconv.Itoa(r.Nodes)},
+			{"Number of Pods", strconv.Itoa(r.Pods)},
+			{"Number of unschedulable Pods", strconv.Itoa(r.PendingPods)},
+			{"Number of non-tainted Nodes", strconv.Itoa(r.NonTaintedNodes)},
+			{"Number of not ready Nodes", strconv.Itoa(r.NotReadyNodes)},
+			{"Excess Nodes", fmt.Sprintf("%.2f", r.ExcessNodes)},
+			{"CPU Pods Consumption", formatMilliCPU(r.CPURequestsMilli)},
+			{"CPU Nodes Allocatable", formatMilliCPU(r.CPUAllocatableMilli)},
+			{"CPU Percentage", common.FormatPercentage(r.PercentageCPU)},
+			{"RAM Pods Consumption", formatBytes(r.MemoryRequestsBytes)},
+			{"RAM Nodes Allocatable", formatBytes(r.MemoryAllocatableBytes)},
+			{"RAM Percentage", common.FormatPercentage(r.PercentageRAM)},
+		}
+		if r.CostPerHour != nil {
+			rows = append(rows,
+				[]string{"Cost per Hour", FormatCurrency(*r.CostPerHour, r.Currency)},
+				[]string{"Excess Cost per Hour", FormatCurrency(*r.ExcessCostPerHour, r.Currency)})
+		}
+		return WriteRows(w, format, "", []string{"Metric", "Value"}, rows)
+	}
+}
+
+// WriteStructured serializes records as indented JSON or as YAML
+func WriteStructured(w io.Writer, format OutputFormat, records interface{}) error {
+	var out []byte
+	var err error
+	switch format {
+	case OutputJSON:
+		out, err = json.MarshalIndent(records, "", "  ")
+		out = append(out, '\n')
+	case OutputYAML:
+		out, err = yaml.Marshal(records)
+	default:
+		return fmt.Errorf("output format %q is not a structured format", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// WriteRows renders a header and its rows as a table, csv or markdown
+func WriteRows(w io.Writer, format OutputFormat, title string, header []string, rows [][]string) error {
+	if format == OutputCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	}
+
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.SetTitle(fmt.Sprintf("Cluster State\n"+
-		"Number of Nodes: %v\n"+
-		"Number of Pods: %v \n"+
-		"Number of non-tainted Nodes: %v\n"+
-		"Excess Nodes: %.2f",
-		clusterManifest.NumberOfNodes, clusterManifest.NumberOfPods, clusterManifest.NumberOfNonTaintedNodes, clusterManifest.ExcessNodes))
-	t.AppendHeader(table.Row{"Resource", "Pods Consumption", "Nodes Allocatable", "Percentage"})
-
-	t.AppendRow(table.Row{"CPU",
-		common.FormatValue("CPU", clusterManifest.TotalPodsMetrics.ReqCPU),
-		common.FormatValue("CPU", clusterManifest.TotalNodeMetrics.AllocCPU),
-		common.FormatPercentage(clusterManifest.Utilization.PercentageCPU)})
-	t.AppendRow(table.Row{"RAM",
-		common.FormatValue("RAM", clusterManifest.TotalPodsMetrics.ReqRAM),
-		common.FormatValue("RAM", clusterManifest.TotalNodeMetrics.AllocRAM),
-		common.FormatPercentage(clusterManifest.Utilization.PercentageRAM)})
-
-	t.Render()
+	t.AppendHeader(toRow(header))
+	for _, row := range rows {
+		t.AppendRow(toRow(row))
+	}
+
+	var out string
+	switch format {
+	case OutputTable:
+		t.SetTitle(title)
+		out = t.Render()
+	case OutputMarkdown:
+		out = t.RenderMarkdown()
+	default:
+		return fmt.Errorf("output format %q is not a tabular format", format)
+	}
+	_, err := io.WriteString(w, out+"\n")
+	return err
+}
+
+func toRow(values []string) table.Row {
+	row := make(table.Row, len(values))
+	for i, value := range values {
+		row[i] = value
+	}
+	return row
+}
+
+func candidateMarker(candidate bool) string {
+	if candidate {
+		return "*"
+	}
+	return ""
+}
+
+// YesNo renders a boolean in a table cell
+func YesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+func formatMilliCPU(milli int64) string {
+	return common.FormatValue("CPU", *resource.NewMilliQuantity(milli, resource.DecimalSI))
+}
+
+func formatBytes(bytes int64) string {
+	return common.FormatValue("RAM", *resource.NewQuantity(bytes, resource.BinarySI))
+}
+
+// formatCost renders the price of a node, empty when unknown
+func formatCost(cost *float64) string {
+	if cost == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", *cost)
+}
+
+// FormatCurrency renders an amount with two decimals followed by its currency, if any
+func FormatCurrency(amount float64, currency string) string {
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", amount, currency))
+}
+
+// SortedNodeNames returns the names of the nodes ordered by ascending utilization score,
+// ties are broken by name
+func SortedNodeNames(nodesMap map[string]NodeManifest) []string {
+	names := make([]string, 0, len(nodesMap))
+	for name := range nodesMap {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		si, sj := nodesMap[names[i]].Utilization.Score, nodesMap[names[j]].Utilization.Score
+		if si != sj {
+			return si < sj
+		}
+		return names[i] < names[j]
+	})
+	return names
 }
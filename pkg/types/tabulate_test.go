@@ -0,0 +1,83 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testNodeManifest(name string, score float64) NodeManifest {
+	node := &v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: name}}
+	node.Status.Allocatable = v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("4Gi")}
+	return NodeManifest{
+		Node:        node,
+		Metrics:     CreateNodeMetricsFromNodeObj(node),
+		Utilization: Utilization{Score: score},
+	}
+}
+
+func testNodesMap() map[string]NodeManifest {
+	return map[string]NodeManifest{
+		"c": testNodeManifest("c", 50),
+		"a": testNodeManifest("a", 10),
+		"b": testNodeManifest("b", 10),
+	}
+}
+
+// TestNodeReportsSorted tests that nodes are sorted by score and then by name
+func TestNodeReportsSorted(t *testing.T) {
+	reports := NewNodeReports(testNodesMap(), "a")
+
+	var names []string
+	for _, r := range reports {
+		names = append(names, r.Name)
+	}
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("Expected nodes sorted as a,b,c, got %v", names)
+	}
+	if !reports[0].Candidate || reports[1].Candidate {
+		t.Errorf("Expected only a to be the candidate, got %+v", reports)
+	}
+}
+
+// TestTabulateNodeMapFormats tests the rendering of the nodes in every output format
+func TestTabulateNodeMapFormats(t *testing.T) {
+	for _, format := range OutputFormats {
+		var buf bytes.Buffer
+		if err := TabulateNodeMap(&buf, format, testNodesMap(), "a"); err != nil {
+			t.Errorf("Unexpected error rendering %s: %v", format, err)
+			continue
+		}
+		out := buf.String()
+
+		switch format {
+		case OutputJSON:
+			var reports []NodeReport
+			if err := json.Unmarshal(buf.Bytes(), &reports); err != nil || len(reports) != 3 {
+				t.Errorf("Expected 3 nodes in JSON output, got %v (%v)", out, err)
+			}
+		case OutputCSV:
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) != 4 || lines[1] != "*,a,no,0,0mi,0MB,2000mi,4295MB,0.00%,0.00%,10.00" {
+				t.Errorf("Unexpected CSV output:\n%s", out)
+			}
+		case OutputYAML:
+			if !strings.Contains(out, "memoryAllocatableBytes: 4294967296") {
+				t.Errorf("Unexpected YAML output:\n%s", out)
+			}
+		case OutputMarkdown:
+			if !strings.HasPrefix(out, "| Candidate | Node |") {
+				t.Errorf("Unexpected markdown output:\n%s", out)
+			}
+		default:
+			if !strings.Contains(out, "4295MB") {
+				t.Errorf("Expected allocatable memory in %s output:\n%s", format, out)
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/SAP/node-refiner/pkg/common"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -13,15 +15,26 @@ const (
 	ramWeight = 0.2
 )
 
+// ActivePodsFieldSelector selects the pods that still occupy resources on their node
+const ActivePodsFieldSelector = "status.phase!=Succeeded,status.phase!=Failed,status.phase!=Unknown"
+
 // ClusterManifest Overall cluster metrics
 type ClusterManifest struct {
 	ExcessNodes             float64
 	NumberOfNonTaintedNodes int
 	NumberOfNodes           int
+	NumberOfNotReadyNodes   int
 	NumberOfPods            int
+	NumberOfPendingPods     int
 	TotalNodeMetrics        NodeMetrics
 	TotalPodsMetrics        PodMetrics
 	Utilization             Utilization
+
+	// Costs are calculated when a price table is set, in its currency
+	Priced            bool
+	Currency          string
+	CostPerHour       float64
+	ExcessCostPerHour float64
 }
 
 // NodeManifest meta-data of the node + the metrics of our concern
@@ -32,6 +45,14 @@ type NodeManifest struct {
 	TotalPodsRequests PodMetrics
 	Pods              []*PodManifest
 	Utilization       Utilization
+
+	// CostPerHour is the price of the node, Priced reports whether the price table knows it
+	CostPerHour float64
+	Priced      bool
+
+	// DrainBlockedBy describes the pod keeping the node from being drained, like
+	// "the excluded pod monitoring/prometheus"
+	DrainBlockedBy string
 }
 
 // NodeMetrics allocatable cpu and ram of a node
@@ -68,6 +89,9 @@ func NewClusterManifest(nodesMap map[string]NodeManifest) ClusterManifest {
 			clusterManifest.TotalPodsMetrics.AddPodMetrics(&nodeManifest.TotalPodsRequests)
 			clusterManifest.TotalNodeMetrics.AddNodeMetrics(nodeManifest.Metrics)
 		}
+		if !IsNodeReady(nodeManifest.Node) {
+			clusterManifest.NumberOfNotReadyNodes++
+		}
 		clusterManifest.NumberOfPods += len(nodeManifest.Pods)
 
 	}
@@ -77,6 +101,16 @@ func NewClusterManifest(nodesMap map[string]NodeManifest) ClusterManifest {
 	return clusterManifest
 }
 
+// NewNodeManifest create a NodeManifest by extracting the relevant information from a Node object
+func NewNodeManifest(node *v1.Node) NodeManifest {
+	return NodeManifest{
+		Node:         node,
+		Metrics:      CreateNodeMetricsFromNodeObj(node),
+		Pods:         make([]*PodManifest, 10),
+		NumberOfPods: 0,
+	}
+}
+
 // NewPodManifest create a PodManifest by extracting the relevant information from a Pod object
 func NewPodManifest(pod *v1.Pod) PodManifest {
 	pm := PodManifest{
@@ -86,6 +120,44 @@ func NewPodManifest(pod *v1.Pod) PodManifest {
 	return pm
 }
 
+// IsActivePod is the in-memory equivalent of ActivePodsFieldSelector
+func IsActivePod(pod *v1.Pod) bool {
+	switch pod.Status.Phase {
+	case v1.PodSucceeded, v1.PodFailed, v1.PodUnknown:
+		return false
+	}
+	return true
+}
+
+// IsUnschedulablePod reports whether the scheduler found no node the pending pod fits on
+func IsUnschedulablePod(pod *v1.Pod) bool {
+	if pod.Spec.NodeName != "" || pod.Status.Phase != v1.PodPending {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled {
+			return condition.Status == v1.ConditionFalse && condition.Reason == v1.PodReasonUnschedulable
+		}
+	}
+	return false
+}
+
+// IsNodeReady reports whether the kubelet of the node reports it Ready
+func IsNodeReady(node *v1.Node) bool {
+	_, ready := NodeReadySince(node)
+	return ready
+}
+
+// NodeReadySince returns the time the node last became Ready, false if it is not Ready
+func NodeReadySince(node *v1.Node) (time.Time, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.LastTransitionTime.Time, condition.Status == v1.ConditionTrue
+		}
+	}
+	return time.Time{}, false
+}
+
 // CreateNodeMetricsFromNodeObj create a NodeMetrics object by extracting the relevant information from a Node object
 func CreateNodeMetricsFromNodeObj(node *v1.Node) *NodeMetrics {
 	status := node.Status